@@ -0,0 +1,83 @@
+package clientip
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const localKey = "client_ip"
+
+// ParseTrustedProxies は "10.0.0.1" のようなアドレスと "10.0.0.0/8" のような CIDR を読む
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Middleware はクライアントの IP アドレスを求めて保存する。IP で取り出せるよう、他のミドルウェアより先に登録する。
+// 接続元が信頼するプロキシの場合だけ header（X-Forwarded-For など）を読み、右から順に信頼するプロキシを飛ばして
+// 最初に見つかったアドレスをクライアントとする。それより左の値はクライアントが自由に書けるので使わない
+func Middleware(header string, trusted []netip.Prefix) fiber.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(c *fiber.Ctx) error {
+		remote, _ := netip.AddrFromSlice(c.Context().RemoteIP())
+		remote = remote.Unmap()
+		ip := remote.String()
+
+		if header != "" && isTrusted(remote) {
+			var hops []string
+			for _, value := range c.Request().Header.PeekAll(header) {
+				for _, hop := range strings.Split(string(value), ",") {
+					hops = append(hops, strings.TrimSpace(hop))
+				}
+			}
+			for i := len(hops) - 1; i >= 0; i-- {
+				if hops[i] == "" {
+					continue
+				}
+				ip = hops[i]
+				addr, err := netip.ParseAddr(hops[i])
+				if err != nil || !isTrusted(addr.Unmap()) {
+					break
+				}
+			}
+		}
+
+		c.Locals(localKey, ip)
+		return c.Next()
+	}
+}
+
+// IP は Middleware が求めたクライアントの IP アドレス。Middleware を通っていない場合は接続元のアドレス
+func IP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals(localKey).(string); ok {
+		return ip
+	}
+	return c.IP()
+}
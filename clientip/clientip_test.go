@@ -0,0 +1,77 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMiddleware(t *testing.T) {
+	// app.Test の接続元は 0.0.0.0
+	tests := []struct {
+		name    string
+		header  string
+		trusted []string
+		values  []string
+		want    string
+	}{
+		{"no proxy header configured", "", []string{"0.0.0.0"}, []string{"203.0.113.1"}, "0.0.0.0"},
+		{"untrusted remote", "X-Forwarded-For", []string{"10.0.0.0/8"}, []string{"203.0.113.1"}, "0.0.0.0"},
+		{"trusted remote without header", "X-Forwarded-For", []string{"0.0.0.0"}, nil, "0.0.0.0"},
+		{"single hop", "X-Forwarded-For", []string{"0.0.0.0"}, []string{"203.0.113.1"}, "203.0.113.1"},
+		{"spoofed left-most value", "X-Forwarded-For", []string{"0.0.0.0"}, []string{"198.51.100.7, 203.0.113.1"}, "203.0.113.1"},
+		{"skips trusted proxies", "X-Forwarded-For", []string{"0.0.0.0", "10.0.0.0/8"}, []string{"198.51.100.7, 203.0.113.1, 10.0.0.2, 10.0.0.3"}, "203.0.113.1"},
+		{"all hops trusted", "X-Forwarded-For", []string{"0.0.0.0", "10.0.0.0/8"}, []string{"10.0.0.1, 10.0.0.2"}, "10.0.0.1"},
+		{"empty hops", "X-Forwarded-For", []string{"0.0.0.0"}, []string{"203.0.113.1, ,"}, "203.0.113.1"},
+		{"repeated headers", "X-Forwarded-For", []string{"0.0.0.0", "10.0.0.0/8"}, []string{"198.51.100.7", "203.0.113.1, 10.0.0.2"}, "203.0.113.1"},
+		{"IPv4-mapped trusted proxy", "X-Forwarded-For", []string{"0.0.0.0", "10.0.0.0/8"}, []string{"203.0.113.1, ::ffff:10.0.0.2"}, "203.0.113.1"},
+		{"custom header", "X-Real-IP", []string{"0.0.0.0"}, []string{"203.0.113.1"}, "203.0.113.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trusted, err := ParseTrustedProxies(tt.trusted)
+			if err != nil {
+				t.Fatalf("ParseTrustedProxies: %v", err)
+			}
+			app := fiber.New()
+			app.Use(Middleware(tt.header, trusted))
+			var got string
+			app.Get("/", func(c *fiber.Ctx) error {
+				got = IP(c)
+				return nil
+			})
+
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			for _, value := range tt.values {
+				req.Header.Add("X-Forwarded-For", value)
+				req.Header.Add("X-Real-IP", value)
+			}
+			if _, err := app.Test(req, -1); err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies([]string{"10.1.2.3/8", "::ffff:192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.0.2.1/32", "2001:db8::/32"}
+	for i, prefix := range prefixes {
+		if prefix.String() != want[i] {
+			t.Errorf("prefix[%d] = %s, want %s", i, prefix, want[i])
+		}
+	}
+
+	for _, value := range []string{"10.0.0.0/33", "proxy.example.com"} {
+		if _, err := ParseTrustedProxies([]string{value}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) succeeded", value)
+		}
+	}
+}
@@ -0,0 +1,624 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
+)
+
+type Config struct {
+	Env             string
+	Port            int
+	FrontendURL     string
+	ShutdownTimeout time.Duration
+	// /readyz でデータベースを確認する際のタイムアウト
+	ReadinessTimeout time.Duration
+	// リクエストボディの最大バイト数
+	BodyLimit int
+	// ロードバランサの後ろで動かす場合に、クライアントの IP アドレスを取るヘッダ（例: X-Forwarded-For）
+	ProxyHeader string
+	// ProxyHeader を信頼するプロキシのアドレスまたは CIDR。接続元がこれ以外の場合はヘッダを使わない
+	TrustedProxies []string
+	// 1イベントに登録できる参加者数の上限。0 は無制限
+	MaxParticipantsPerEvent int
+	// 1イベントの候補日数の上限。0 は無制限
+	MaxCandidateDatesPerEvent int
+
+	Log       LogConfig
+	Tracing   TracingConfig
+	Database  DatabaseConfig
+	CORS      CORSConfig
+	Security  SecurityConfig
+	Auth      AuthConfig
+	OIDC      OIDCConfig
+	Scheduler SchedulerConfig
+	RateLimit RateLimitConfig
+}
+
+type LogConfig struct {
+	// debug, info, warn, error のいずれか。debug では SQL も出力する
+	Level string
+	// json または text
+	Format string
+}
+
+type TracingConfig struct {
+	// none, stdout, otlp のいずれか
+	Exporter string
+	// OTLP/HTTP の送信先（例: http://localhost:4318）。空の場合は OTEL_EXPORTER_OTLP_* の標準の設定に従う
+	OTLPEndpoint string
+	ServiceName  string
+	// サンプリングする割合（0〜1）
+	SampleRatio float64
+}
+
+type DatabaseConfig struct {
+	URL               string
+	ConnectRetries    int
+	ConnectRetryDelay time.Duration
+	MaxOpenConns      int
+	MaxIdleConns      int
+	ConnMaxLifetime   time.Duration
+}
+
+type CORSConfig struct {
+	// 未設定の場合は FRONTEND_URL のオリジン、それもなければ *
+	AllowOrigins []string
+	// Cookie などの認証情報付きのリクエストを許可する。AllowOrigins に * は使えない
+	AllowCredentials bool
+}
+
+type AuthConfig struct {
+	// ログインセッションの有効期間
+	SessionTTL time.Duration
+	// セッション Cookie に Secure 属性を付ける。デフォルトは本番環境のみ
+	CookieSecure bool
+	// Lax, Strict, None のいずれか。フロントエンドが別のサイトにある場合は None（Secure 必須）
+	CookieSameSite string
+}
+
+// OIDCConfig は OpenID Connect でのログインの設定。IssuerURL が空の場合は無効
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// プロバイダからのリダイレクト先。この API の /api/v1/auth/oidc/callback を指定する
+	RedirectURL string
+	Scopes      []string
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+type SecurityConfig struct {
+	// HTTPS でのリクエストに付ける Strict-Transport-Security の max-age（秒）。0 で付けない
+	HSTSMaxAge int
+	// /metrics に Authorization: Bearer で送るトークン。空の場合は /metrics を公開しない
+	MetricsToken string
+}
+
+// RateLimit は Window ごとに Limit 回までリクエストを許可する。Limit が 0 の場合は制限しない
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+func (r RateLimit) String() string {
+	if r.Limit == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", r.Limit, r.Window)
+}
+
+type RateLimitConfig struct {
+	// memory（プロセス内）または database（複数のレプリカで共有）
+	Store            string
+	CreateEventPerIP RateLimit
+	RegisterPerIP    RateLimit
+	RegisterPerEvent RateLimit
+	AuthPerIP        RateLimit
+}
+
+type SchedulerConfig struct {
+	Enabled  bool
+	Spec     string
+	TimeZone string
+	// 1回のジョブの実行時間の上限。超えたジョブはキャンセルし、/status で stuck として報告する
+	JobTimeout time.Duration
+}
+
+func (c Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+func defaults() Config {
+	return Config{
+		Env:              "development",
+		Port:             8080,
+		ShutdownTimeout:  20 * time.Second,
+		ReadinessTimeout: 2 * time.Second,
+		BodyLimit:        256 * 1024,
+
+		MaxParticipantsPerEvent:   300,
+		MaxCandidateDatesPerEvent: 100,
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "yotei-backend",
+			SampleRatio: 1,
+		},
+		Database: DatabaseConfig{
+			ConnectRetries:    4,
+			ConnectRetryDelay: 20 * time.Second,
+			MaxOpenConns:      10,
+			MaxIdleConns:      5,
+			ConnMaxLifetime:   30 * time.Minute,
+		},
+		Security: SecurityConfig{
+			HSTSMaxAge: 365 * 24 * 60 * 60,
+		},
+		Auth: AuthConfig{
+			SessionTTL:     30 * 24 * time.Hour,
+			CookieSameSite: "Lax",
+		},
+		OIDC: OIDCConfig{
+			Scopes: []string{"openid", "email", "profile"},
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:    true,
+			Spec:       "@every 1m",
+			TimeZone:   "Asia/Tokyo",
+			JobTimeout: 5 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Store:            "memory",
+			CreateEventPerIP: RateLimit{Limit: 20, Window: time.Hour},
+			RegisterPerIP:    RateLimit{Limit: 60, Window: 10 * time.Minute},
+			RegisterPerEvent: RateLimit{Limit: 60, Window: time.Minute},
+			AuthPerIP:        RateLimit{Limit: 10, Window: time.Minute},
+		},
+	}
+}
+
+// Load はデフォルト値 < .env < 環境変数 < コマンドラインフラグ の順に設定を読み込み、検証する。
+// args はサブコマンドより前のグローバルフラグで、解析後の残りの引数を返す
+func Load(args []string) (Config, []string, error) {
+	flags := flag.NewFlagSet("yotei-backend", flag.ContinueOnError)
+	envFile := flags.String("env-file", ".env", "dotenv file to load outside production")
+	databaseURL := flags.String("database-url", "", "database URL (overrides DATABASE_URL)")
+	port := flags.Int("port", 0, "HTTP port (overrides PORT)")
+	frontendURL := flags.String("frontend-url", "", "frontend base URL (overrides FRONTEND_URL)")
+	if err := flags.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if os.Getenv("ENV") != "production" {
+		if err := godotenv.Load(*envFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return Config{}, nil, fmt.Errorf("failed to load %s: %w", *envFile, err)
+		}
+	}
+
+	cfg := defaults()
+	var errs []error
+	env := envReader{errs: &errs}
+
+	env.string("ENV", &cfg.Env)
+	env.int("PORT", &cfg.Port)
+	env.string("FRONTEND_URL", &cfg.FrontendURL)
+	env.duration("SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+	env.duration("READINESS_TIMEOUT", &cfg.ReadinessTimeout)
+	env.int("BODY_LIMIT", &cfg.BodyLimit)
+	env.string("PROXY_HEADER", &cfg.ProxyHeader)
+	env.list("TRUSTED_PROXIES", &cfg.TrustedProxies)
+	env.int("MAX_PARTICIPANTS_PER_EVENT", &cfg.MaxParticipantsPerEvent)
+	env.int("MAX_CANDIDATE_DATES_PER_EVENT", &cfg.MaxCandidateDatesPerEvent)
+
+	env.string("LOG_LEVEL", &cfg.Log.Level)
+	env.string("LOG_FORMAT", &cfg.Log.Format)
+
+	env.string("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	env.string("OTEL_EXPORTER_OTLP_ENDPOINT", &cfg.Tracing.OTLPEndpoint)
+	env.string("OTEL_SERVICE_NAME", &cfg.Tracing.ServiceName)
+	env.float("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+
+	env.string("DATABASE_URL", &cfg.Database.URL)
+	env.int("DB_CONNECT_RETRIES", &cfg.Database.ConnectRetries)
+	env.duration("DB_CONNECT_RETRY_DELAY", &cfg.Database.ConnectRetryDelay)
+	env.int("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	env.int("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	env.duration("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+
+	env.list("CORS_ALLOW_ORIGINS", &cfg.CORS.AllowOrigins)
+	env.bool("CORS_ALLOW_CREDENTIALS", &cfg.CORS.AllowCredentials)
+	env.int("HSTS_MAX_AGE", &cfg.Security.HSTSMaxAge)
+	env.string("METRICS_TOKEN", &cfg.Security.MetricsToken)
+
+	cfg.Auth.CookieSecure = cfg.IsProduction()
+	env.duration("SESSION_TTL", &cfg.Auth.SessionTTL)
+	env.bool("SESSION_COOKIE_SECURE", &cfg.Auth.CookieSecure)
+	env.string("SESSION_COOKIE_SAMESITE", &cfg.Auth.CookieSameSite)
+
+	env.string("OIDC_ISSUER_URL", &cfg.OIDC.IssuerURL)
+	env.string("OIDC_CLIENT_ID", &cfg.OIDC.ClientID)
+	env.string("OIDC_CLIENT_SECRET", &cfg.OIDC.ClientSecret)
+	env.string("OIDC_REDIRECT_URL", &cfg.OIDC.RedirectURL)
+	env.list("OIDC_SCOPES", &cfg.OIDC.Scopes)
+
+	env.bool("SCHEDULER_ENABLED", &cfg.Scheduler.Enabled)
+	env.string("SCHEDULER_SPEC", &cfg.Scheduler.Spec)
+	env.string("SCHEDULER_TIMEZONE", &cfg.Scheduler.TimeZone)
+	env.duration("SCHEDULER_JOB_TIMEOUT", &cfg.Scheduler.JobTimeout)
+
+	env.string("RATE_LIMIT_STORE", &cfg.RateLimit.Store)
+	env.rateLimit("RATE_LIMIT_CREATE_EVENT_PER_IP", &cfg.RateLimit.CreateEventPerIP)
+	env.rateLimit("RATE_LIMIT_REGISTER_PER_IP", &cfg.RateLimit.RegisterPerIP)
+	env.rateLimit("RATE_LIMIT_REGISTER_PER_EVENT", &cfg.RateLimit.RegisterPerEvent)
+	env.rateLimit("RATE_LIMIT_AUTH_PER_IP", &cfg.RateLimit.AuthPerIP)
+
+	if *databaseURL != "" {
+		cfg.Database.URL = *databaseURL
+	}
+	if *port != 0 {
+		cfg.Port = *port
+	}
+	if *frontendURL != "" {
+		cfg.FrontendURL = *frontendURL
+	}
+
+	if len(cfg.CORS.AllowOrigins) == 0 {
+		cfg.CORS.AllowOrigins = []string{"*"}
+		if origin := originOf(cfg.FrontendURL); origin != "" {
+			cfg.CORS.AllowOrigins = []string{origin}
+		}
+	}
+
+	errs = append(errs, cfg.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("DATABASE_URL is required"))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %d", c.Port))
+	}
+	if c.FrontendURL != "" {
+		if u, err := url.Parse(c.FrontendURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("FRONTEND_URL must be an absolute http(s) URL, got %q", c.FrontendURL))
+		}
+	}
+
+	if c.ProxyHeader != "" && len(c.TrustedProxies) == 0 {
+		errs = append(errs, errors.New("TRUSTED_PROXIES is required when PROXY_HEADER is set"))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if !validProxy(proxy) {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES must contain IP addresses or CIDRs, got %q", proxy))
+		}
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.ReadinessTimeout <= 0 {
+		errs = append(errs, errors.New("READINESS_TIMEOUT must be positive"))
+	}
+	if c.BodyLimit <= 0 {
+		errs = append(errs, errors.New("BODY_LIMIT must be positive"))
+	}
+	if c.MaxParticipantsPerEvent < 0 {
+		errs = append(errs, errors.New("MAX_PARTICIPANTS_PER_EVENT must not be negative"))
+	}
+	if c.MaxCandidateDatesPerEvent < 0 {
+		errs = append(errs, errors.New("MAX_CANDIDATE_DATES_PER_EVENT must not be negative"))
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error, got %q", c.Log.Level))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be json or text, got %q", c.Log.Format))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.OTLPEndpoint != "" {
+			if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT must be an absolute http(s) URL, got %q", c.Tracing.OTLPEndpoint))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER must be one of none, stdout, otlp, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+
+	if c.Database.ConnectRetries < 1 {
+		errs = append(errs, errors.New("DB_CONNECT_RETRIES must be at least 1"))
+	}
+	if c.Database.ConnectRetryDelay < 0 {
+		errs = append(errs, errors.New("DB_CONNECT_RETRY_DELAY must not be negative"))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative"))
+	}
+
+	if len(c.CORS.AllowOrigins) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOW_ORIGINS must not be empty"))
+	}
+	for _, origin := range c.CORS.AllowOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				errs = append(errs, errors.New("CORS_ALLOW_ORIGINS must list explicit origins when CORS_ALLOW_CREDENTIALS is true"))
+			}
+			continue
+		}
+		if originOf(origin) != origin {
+			errs = append(errs, fmt.Errorf("CORS_ALLOW_ORIGINS must contain origins such as https://example.com, got %q", origin))
+		}
+	}
+	if c.Security.HSTSMaxAge < 0 {
+		errs = append(errs, errors.New("HSTS_MAX_AGE must not be negative"))
+	}
+
+	if c.Auth.SessionTTL <= 0 {
+		errs = append(errs, errors.New("SESSION_TTL must be positive"))
+	}
+	switch c.Auth.CookieSameSite {
+	case "Lax", "Strict":
+	case "None":
+		if !c.Auth.CookieSecure {
+			errs = append(errs, errors.New("SESSION_COOKIE_SAMESITE=None requires SESSION_COOKIE_SECURE=true"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("SESSION_COOKIE_SAMESITE must be one of Lax, Strict, None, got %q", c.Auth.CookieSameSite))
+	}
+
+	if _, err := cron.ParseStandard(c.Scheduler.Spec); err != nil {
+		errs = append(errs, fmt.Errorf("SCHEDULER_SPEC is invalid: %w", err))
+	}
+	if _, err := time.LoadLocation(c.Scheduler.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("SCHEDULER_TIMEZONE is invalid: %w", err))
+	}
+	if c.Scheduler.JobTimeout <= 0 {
+		errs = append(errs, errors.New("SCHEDULER_JOB_TIMEOUT must be positive"))
+	}
+
+	if c.OIDC.Enabled() {
+		if originOf(c.OIDC.IssuerURL) == "" {
+			errs = append(errs, fmt.Errorf("OIDC_ISSUER_URL must be an absolute http(s) URL, got %q", c.OIDC.IssuerURL))
+		}
+		if c.OIDC.ClientID == "" {
+			errs = append(errs, errors.New("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set"))
+		}
+		if originOf(c.OIDC.RedirectURL) == "" {
+			errs = append(errs, errors.New("OIDC_REDIRECT_URL must be an absolute http(s) URL when OIDC_ISSUER_URL is set"))
+		}
+		if !slices.Contains(c.OIDC.Scopes, "openid") {
+			errs = append(errs, errors.New("OIDC_SCOPES must include openid"))
+		}
+		// コールバック後のリダイレクト先がないとログインを完了できない
+		if c.FrontendURL == "" {
+			errs = append(errs, errors.New("FRONTEND_URL is required when OIDC_ISSUER_URL is set"))
+		}
+	}
+
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "database" {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_STORE must be memory or database, got %q", c.RateLimit.Store))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted はパスワードなどを伏せた設定の一覧を返す。起動時のログ出力用
+func (c Config) Redacted() []string {
+	return []string{
+		"ENV=" + c.Env,
+		"PORT=" + strconv.Itoa(c.Port),
+		"FRONTEND_URL=" + c.FrontendURL,
+		"SHUTDOWN_TIMEOUT=" + c.ShutdownTimeout.String(),
+		"READINESS_TIMEOUT=" + c.ReadinessTimeout.String(),
+		"BODY_LIMIT=" + strconv.Itoa(c.BodyLimit),
+		"PROXY_HEADER=" + c.ProxyHeader,
+		"TRUSTED_PROXIES=" + strings.Join(c.TrustedProxies, ","),
+		"MAX_PARTICIPANTS_PER_EVENT=" + strconv.Itoa(c.MaxParticipantsPerEvent),
+		"MAX_CANDIDATE_DATES_PER_EVENT=" + strconv.Itoa(c.MaxCandidateDatesPerEvent),
+		"LOG_LEVEL=" + c.Log.Level,
+		"LOG_FORMAT=" + c.Log.Format,
+		"TRACING_EXPORTER=" + c.Tracing.Exporter,
+		"OTEL_EXPORTER_OTLP_ENDPOINT=" + redactURL(c.Tracing.OTLPEndpoint),
+		"OTEL_SERVICE_NAME=" + c.Tracing.ServiceName,
+		"TRACING_SAMPLE_RATIO=" + strconv.FormatFloat(c.Tracing.SampleRatio, 'g', -1, 64),
+		"DATABASE_URL=" + redactURL(c.Database.URL),
+		"DB_CONNECT_RETRIES=" + strconv.Itoa(c.Database.ConnectRetries),
+		"DB_CONNECT_RETRY_DELAY=" + c.Database.ConnectRetryDelay.String(),
+		"DB_MAX_OPEN_CONNS=" + strconv.Itoa(c.Database.MaxOpenConns),
+		"DB_MAX_IDLE_CONNS=" + strconv.Itoa(c.Database.MaxIdleConns),
+		"DB_CONN_MAX_LIFETIME=" + c.Database.ConnMaxLifetime.String(),
+		"CORS_ALLOW_ORIGINS=" + strings.Join(c.CORS.AllowOrigins, ","),
+		"CORS_ALLOW_CREDENTIALS=" + strconv.FormatBool(c.CORS.AllowCredentials),
+		"HSTS_MAX_AGE=" + strconv.Itoa(c.Security.HSTSMaxAge),
+		"METRICS_TOKEN=" + redactSecret(c.Security.MetricsToken),
+		"SESSION_TTL=" + c.Auth.SessionTTL.String(),
+		"SESSION_COOKIE_SECURE=" + strconv.FormatBool(c.Auth.CookieSecure),
+		"SESSION_COOKIE_SAMESITE=" + c.Auth.CookieSameSite,
+		"OIDC_ISSUER_URL=" + c.OIDC.IssuerURL,
+		"OIDC_CLIENT_ID=" + c.OIDC.ClientID,
+		"OIDC_CLIENT_SECRET=" + redactSecret(c.OIDC.ClientSecret),
+		"OIDC_REDIRECT_URL=" + c.OIDC.RedirectURL,
+		"OIDC_SCOPES=" + strings.Join(c.OIDC.Scopes, ","),
+		"SCHEDULER_ENABLED=" + strconv.FormatBool(c.Scheduler.Enabled),
+		"SCHEDULER_SPEC=" + c.Scheduler.Spec,
+		"SCHEDULER_TIMEZONE=" + c.Scheduler.TimeZone,
+		"SCHEDULER_JOB_TIMEOUT=" + c.Scheduler.JobTimeout.String(),
+		"RATE_LIMIT_STORE=" + c.RateLimit.Store,
+		"RATE_LIMIT_CREATE_EVENT_PER_IP=" + c.RateLimit.CreateEventPerIP.String(),
+		"RATE_LIMIT_REGISTER_PER_IP=" + c.RateLimit.RegisterPerIP.String(),
+		"RATE_LIMIT_REGISTER_PER_EVENT=" + c.RateLimit.RegisterPerEvent.String(),
+		"RATE_LIMIT_AUTH_PER_IP=" + c.RateLimit.AuthPerIP.String(),
+	}
+}
+
+// originOf は URL のスキームとホストだけを返す。http(s) の URL でなければ空文字を返す
+func originOf(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// validProxy は "10.0.0.1" のような IP アドレスか "10.0.0.0/8" のような CIDR かどうか
+func validProxy(value string) bool {
+	if _, err := netip.ParsePrefix(value); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(value)
+	return err == nil
+}
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "xxxxx"
+}
+
+// dsnPassword は "host=... password=..." 形式の DSN に含まれるパスワードにマッチする
+var dsnPassword = regexp.MustCompile(`(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s']\S*)`)
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		// URL 形式でなければキーワード形式の DSN として扱う
+		return dsnPassword.ReplaceAllString(raw, "${1}xxxxx")
+	}
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+	}
+	query := u.Query()
+	if query.Has("password") {
+		query.Set("password", "xxxxx")
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+type envReader struct {
+	errs *[]error
+}
+
+func (r envReader) lookup(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
+
+func (r envReader) string(key string, dst *string) {
+	if value, ok := r.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (r envReader) int(key string, dst *int) {
+	if value, ok := r.lookup(key); ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (r envReader) float(key string, dst *float64) {
+	if value, ok := r.lookup(key); ok {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be a number, got %q", key, value))
+			return
+		}
+		*dst = f
+	}
+}
+
+func (r envReader) bool(key string, dst *bool) {
+	if value, ok := r.lookup(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be a boolean, got %q", key, value))
+			return
+		}
+		*dst = b
+	}
+}
+
+func (r envReader) duration(key string, dst *time.Duration) {
+	if value, ok := r.lookup(key); ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be a duration such as 20s, got %q", key, value))
+			return
+		}
+		*dst = d
+	}
+}
+
+func (r envReader) list(key string, dst *[]string) {
+	if value, ok := r.lookup(key); ok {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*dst = items
+	}
+}
+
+// rateLimit は "20/1h" のような「回数/期間」を読む。"off" または "0" で制限しない
+func (r envReader) rateLimit(key string, dst *RateLimit) {
+	if value, ok := r.lookup(key); ok {
+		if value == "off" || value == "0" {
+			*dst = RateLimit{}
+			return
+		}
+		limitStr, windowStr, _ := strings.Cut(value, "/")
+		limit, err := strconv.Atoi(limitStr)
+		window, windowErr := time.ParseDuration(windowStr)
+		if err != nil || windowErr != nil || limit < 1 || window <= 0 {
+			*r.errs = append(*r.errs, fmt.Errorf("%s must be a limit such as 20/1h or off, got %q", key, value))
+			return
+		}
+		*dst = RateLimit{Limit: limit, Window: window}
+	}
+}
@@ -1,59 +1,130 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"strings"
 	"time"
 
-	"yotei-backend/models"
+	"yotei-backend/config"
+	"yotei-backend/logging"
+	"yotei-backend/telemetry"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-func Connect() error {
-	var err error
+// DATABASE_URL からドライバを選ぶ。SQLite と判断できないものは PostgreSQL の URL かキーワード形式の DSN として扱う
+//
+//	sqlite://path/to/file.db / file.db / :memory: -> SQLite
+//	postgres://... / host=localhost user=yotei ... -> PostgreSQL
+func dialectorFor(databaseURL string) gorm.Dialector {
+	switch {
+	case strings.HasPrefix(databaseURL, "sqlite://"):
+		return sqlite.Open(sqliteDSN(strings.TrimPrefix(databaseURL, "sqlite://")))
+	case strings.HasPrefix(databaseURL, "file:"),
+		databaseURL == ":memory:",
+		strings.HasSuffix(databaseURL, ".db"),
+		strings.HasSuffix(databaseURL, ".sqlite"),
+		strings.HasSuffix(databaseURL, ".sqlite3"):
+		return sqlite.Open(sqliteDSN(databaseURL))
+	}
+	return postgres.Open(databaseURL)
+}
 
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		return fmt.Errorf("DATABASE_URL environment variable is not set")
+// 外部キー制約（ON DELETE CASCADE）を有効にし、ロック待ちでエラーにならないようにする
+func sqliteDSN(path string) string {
+	if strings.Contains(path, "_pragma=") {
+		return path
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
 	}
+	return path + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
+
+func IsSQLite() bool {
+	return DB != nil && DB.Dialector.Name() == "sqlite"
+}
 
-	for i := 0; i < 4; i++ {
-		DB, err = gorm.Open(postgres.Open(databaseURL), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
+func Connect(cfg config.DatabaseConfig) error {
+	dialector := dialectorFor(cfg.URL)
+
+	// SQL は LOG_LEVEL=debug のときだけ出力し、エラーと遅いクエリは常に出力する
+	gormLogger := logging.GormLogger{SlowThreshold: 200 * time.Millisecond}
+
+	var err error
+	for i := 0; i < cfg.ConnectRetries; i++ {
+		DB, err = gorm.Open(dialector, &gorm.Config{
+			Logger:         gormLogger,
+			TranslateError: true,
 		})
-		if err == nil {
+		if err == nil || i == cfg.ConnectRetries-1 {
 			break
 		}
-		time.Sleep(20 * time.Second)
+		slog.Warn("Database connection failed, retrying",
+			"attempt", i+1, "max_attempts", cfg.ConnectRetries, "retry_in", cfg.ConnectRetryDelay.String(), "error", err)
+		time.Sleep(cfg.ConnectRetryDelay)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	log.Println("Database connection established")
+	if err := DB.Use(telemetry.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to enable database tracing: %w", err)
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	if IsSQLite() {
+		// SQLite は書き込みを1接続に制限する。:memory: も接続ごとに別DBになるのを防ぐ
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+
+	slog.Info("Database connection established")
 	return nil
 }
 
 func Migrate() error {
-	log.Println("Running database migrations...")
-
-	err := DB.AutoMigrate(
-		&models.Event{},
-		&models.CandidateDate{},
-		&models.Participant{},
-		&models.Response{},
-		&models.RSSFeed{},
-	)
-	if err != nil {
+	slog.Info("Running database migrations")
+
+	if err := MigrateUp(context.Background()); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	log.Println("Database migrations completed successfully")
+	slog.Info("Database migrations completed successfully")
 	return nil
 }
+
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database is not connected")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
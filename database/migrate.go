@@ -0,0 +1,286 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// 複数のレプリカが同時に起動してもマイグレーションが一度だけ走るようにするためのロックキー
+const migrationLockKey = 72616_0001
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// migrations/<dialect>/<version>_<name>.(up|down).sql を読み込む
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q: %w", dialect, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name: %s", fileName)
+		}
+		versionStr, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", fileName, err)
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// withMigrationLock はマイグレーション用の接続を確保し、PostgreSQL ではアドバイザリロックを取得して fn を実行する
+func withMigrationLock(ctx context.Context, fn func(conn *sql.Conn, migrations []Migration) error) error {
+	if DB == nil {
+		return fmt.Errorf("database is not connected")
+	}
+
+	dialect := DB.Dialector.Name()
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if dialect == "postgres" {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       varchar(255) NOT NULL,
+		applied_at timestamp NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return fn(conn, migrations)
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func placeholders(n int) []string {
+	result := make([]string, n)
+	for i := range result {
+		if DB.Dialector.Name() == "postgres" {
+			result[i] = "$" + strconv.Itoa(i+1)
+		} else {
+			result[i] = "?"
+		}
+	}
+	return result
+}
+
+func runMigration(ctx context.Context, conn *sql.Conn, m Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script, verb := m.Down, "down"
+	if up {
+		script, verb = m.Up, "up"
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %04d_%s %s failed: %w", m.Version, m.Name, verb, err)
+	}
+
+	p := placeholders(3)
+	if up {
+		_, err = tx.ExecContext(ctx,
+			fmt.Sprintf("INSERT INTO schema_migrations (version, name, applied_at) VALUES (%s, %s, %s)", p[0], p[1], p[2]),
+			m.Version, m.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %s", p[0]), m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// MigrateUp は未適用のマイグレーションをすべて適用する
+func MigrateUp(ctx context.Context) error {
+	return withMigrationLock(ctx, func(conn *sql.Conn, migrations []Migration) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			slog.Info("Applying migration", "version", m.Version, "name", m.Name)
+			if err := runMigration(ctx, conn, m, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateDown は適用済みのマイグレーションを新しいものから steps 件戻す
+func MigrateDown(ctx context.Context, steps int) error {
+	return withMigrationLock(ctx, func(conn *sql.Conn, migrations []Migration) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			slog.Info("Reverting migration", "version", m.Version, "name", m.Name)
+			if err := runMigration(ctx, conn, m, false); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// GetMigrationStatus はマイグレーションごとの適用状況を返す。読み取りだけなので、ロックの取得や schema_migrations の作成はしない
+func GetMigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database is not connected")
+	}
+	migrations, err := loadMigrations(DB.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	// schema_migrations がなければ一度もマイグレーションしていないので、すべて未適用
+	applied := map[int]time.Time{}
+	if DB.WithContext(ctx).Migrator().HasTable("schema_migrations") {
+		sqlDB, err := DB.DB()
+		if err != nil {
+			return nil, err
+		}
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		if applied, err = appliedMigrations(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// MigrationVersion は適用済みの最新バージョンと、バイナリに含まれる最新バージョンを返す
+func MigrationVersion(ctx context.Context) (current int, latest int, err error) {
+	if DB == nil {
+		return 0, 0, fmt.Errorf("database is not connected")
+	}
+	migrations, err := loadMigrations(DB.Dialector.Name())
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	var version sql.NullInt64
+	if err := DB.WithContext(ctx).Raw("SELECT MAX(version) FROM schema_migrations").Scan(&version).Error; err != nil {
+		return 0, latest, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return int(version.Int64), latest, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"yotei-backend/config"
+)
+
+func connectSQLite(t *testing.T) {
+	t.Helper()
+
+	if err := Connect(config.DatabaseConfig{URL: filepath.Join(t.TempDir(), "yotei.db"), ConnectRetries: 1}); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := Close(); err != nil {
+			t.Errorf("close database: %v", err)
+		}
+	})
+}
+
+// pending は適用されていないマイグレーションのバージョンを返す
+func pending(t *testing.T) []int {
+	t.Helper()
+
+	statuses, err := GetMigrationStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetMigrationStatus: %v", err)
+	}
+	versions := []int{}
+	for _, status := range statuses {
+		if !status.Applied {
+			versions = append(versions, status.Version)
+		}
+	}
+	return versions
+}
+
+func TestMigrations(t *testing.T) {
+	connectSQLite(t)
+	ctx := context.Background()
+
+	migrations, err := loadMigrations("sqlite")
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	// 状況の確認だけではテーブルを作らない
+	if got := pending(t); len(got) != len(migrations) {
+		t.Errorf("pending before migrating = %v, want all %d", got, len(migrations))
+	}
+	if DB.Migrator().HasTable("schema_migrations") {
+		t.Error("GetMigrationStatus created schema_migrations")
+	}
+
+	if err := MigrateUp(ctx); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	if got := pending(t); len(got) != 0 {
+		t.Errorf("pending after MigrateUp = %v", got)
+	}
+	if current, want, err := MigrationVersion(ctx); err != nil || current != latest || want != latest {
+		t.Errorf("MigrationVersion = %d, %d, %v; want %d", current, want, err, latest)
+	}
+
+	// 戻したあとにもう一度適用できる
+	if err := MigrateDown(ctx, 2); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if got := pending(t); len(got) != 2 || got[0] != migrations[len(migrations)-2].Version || got[1] != latest {
+		t.Errorf("pending after MigrateDown(2) = %v", got)
+	}
+	if err := MigrateUp(ctx); err != nil {
+		t.Fatalf("MigrateUp again: %v", err)
+	}
+	if got := pending(t); len(got) != 0 {
+		t.Errorf("pending after migrating again = %v", got)
+	}
+}
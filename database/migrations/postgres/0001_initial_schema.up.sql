@@ -0,0 +1,62 @@
+-- AutoMigrate で作成済みの環境でもそのまま適用できるよう IF NOT EXISTS を付けている
+CREATE TABLE IF NOT EXISTS events (
+    id                      varchar(36) PRIMARY KEY,
+    title                   varchar(255) NOT NULL,
+    description             text,
+    creator_name            varchar(100),
+    created_at              timestamptz,
+    updated_at              timestamptz,
+    deadline_reached        boolean DEFAULT false,
+    auto_decision_reached   boolean DEFAULT false,
+    allow_setting_changes   boolean DEFAULT true,
+    deadline_enable         boolean DEFAULT false,
+    deadline                timestamp,
+    auto_decision_enable    boolean DEFAULT false,
+    auto_decision_threshold bigint DEFAULT 0,
+    rss_enabled             boolean DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS candidate_dates (
+    id         bigserial PRIMARY KEY,
+    event_id   varchar(36) NOT NULL,
+    date_time  timestamptz NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_events_candidate_dates FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_candidate_dates_event_id ON candidate_dates (event_id);
+CREATE INDEX IF NOT EXISTS idx_candidate_dates_deleted_at ON candidate_dates (deleted_at);
+
+CREATE TABLE IF NOT EXISTS participants (
+    id         bigserial PRIMARY KEY,
+    event_id   varchar(36) NOT NULL,
+    name       varchar(100) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_events_participants FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_participants_event_id ON participants (event_id);
+
+CREATE TABLE IF NOT EXISTS responses (
+    id                bigserial PRIMARY KEY,
+    participant_id    bigint NOT NULL,
+    candidate_date_id bigint NOT NULL,
+    status            varchar(20) NOT NULL,
+    created_at        timestamptz,
+    updated_at        timestamptz,
+    CONSTRAINT fk_participants_responses FOREIGN KEY (participant_id) REFERENCES participants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_candidate_dates_responses FOREIGN KEY (candidate_date_id) REFERENCES candidate_dates (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_responses_participant_id ON responses (participant_id);
+CREATE INDEX IF NOT EXISTS idx_responses_candidate_date_id ON responses (candidate_date_id);
+
+CREATE TABLE IF NOT EXISTS rss_feeds (
+    id          bigserial PRIMARY KEY,
+    event_id    varchar(36) NOT NULL,
+    title       varchar(255) NOT NULL,
+    link        varchar(255) NOT NULL,
+    description text NOT NULL,
+    created_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_rss_feeds_event_id ON rss_feeds (event_id);
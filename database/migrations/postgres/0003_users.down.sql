@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_events_owner_id;
+ALTER TABLE events DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
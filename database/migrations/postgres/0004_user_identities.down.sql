@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_participants_event_user;
+ALTER TABLE participants DROP COLUMN IF EXISTS user_id;
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS rss_feeds;
+DROP TABLE IF EXISTS responses;
+DROP TABLE IF EXISTS participants;
+DROP TABLE IF EXISTS candidate_dates;
+DROP TABLE IF EXISTS events;
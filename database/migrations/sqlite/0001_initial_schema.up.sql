@@ -0,0 +1,61 @@
+CREATE TABLE IF NOT EXISTS events (
+    id                      varchar(36) PRIMARY KEY,
+    title                   varchar(255) NOT NULL,
+    description             text,
+    creator_name            varchar(100),
+    created_at              datetime,
+    updated_at              datetime,
+    deadline_reached        numeric DEFAULT false,
+    auto_decision_reached   numeric DEFAULT false,
+    allow_setting_changes   numeric DEFAULT true,
+    deadline_enable         numeric DEFAULT false,
+    deadline                timestamp,
+    auto_decision_enable    numeric DEFAULT false,
+    auto_decision_threshold integer DEFAULT 0,
+    rss_enabled             numeric DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS candidate_dates (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    event_id   varchar(36) NOT NULL,
+    date_time  datetime NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    CONSTRAINT fk_events_candidate_dates FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_candidate_dates_event_id ON candidate_dates (event_id);
+CREATE INDEX IF NOT EXISTS idx_candidate_dates_deleted_at ON candidate_dates (deleted_at);
+
+CREATE TABLE IF NOT EXISTS participants (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    event_id   varchar(36) NOT NULL,
+    name       varchar(100) NOT NULL,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_events_participants FOREIGN KEY (event_id) REFERENCES events (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_participants_event_id ON participants (event_id);
+
+CREATE TABLE IF NOT EXISTS responses (
+    id                integer PRIMARY KEY AUTOINCREMENT,
+    participant_id    integer NOT NULL,
+    candidate_date_id integer NOT NULL,
+    status            varchar(20) NOT NULL,
+    created_at        datetime,
+    updated_at        datetime,
+    CONSTRAINT fk_participants_responses FOREIGN KEY (participant_id) REFERENCES participants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_candidate_dates_responses FOREIGN KEY (candidate_date_id) REFERENCES candidate_dates (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_responses_participant_id ON responses (participant_id);
+CREATE INDEX IF NOT EXISTS idx_responses_candidate_date_id ON responses (candidate_date_id);
+
+CREATE TABLE IF NOT EXISTS rss_feeds (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    event_id    varchar(36) NOT NULL,
+    title       varchar(255) NOT NULL,
+    link        varchar(255) NOT NULL,
+    description text NOT NULL,
+    created_at  datetime
+);
+CREATE INDEX IF NOT EXISTS idx_rss_feeds_event_id ON rss_feeds (event_id);
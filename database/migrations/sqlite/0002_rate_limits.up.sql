@@ -0,0 +1,9 @@
+-- RATE_LIMIT_STORE=database のときに使う固定ウィンドウのカウンタ
+CREATE TABLE rate_limits (
+    limit_key    varchar(255) PRIMARY KEY,
+    window_start datetime NOT NULL,
+    expires_at   datetime NOT NULL,
+    count        integer NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_expires_at ON rate_limits (expires_at);
@@ -0,0 +1,24 @@
+CREATE TABLE users (
+    id            varchar(36) PRIMARY KEY,
+    email         varchar(255) NOT NULL,
+    name          varchar(100) NOT NULL,
+    password_hash varchar(255),
+    created_at    datetime,
+    updated_at    datetime
+);
+
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+
+CREATE TABLE sessions (
+    id         varchar(64) PRIMARY KEY,
+    user_id    varchar(36) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at datetime NOT NULL,
+    created_at datetime
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+-- 匿名で作成したイベントは NULL のまま
+ALTER TABLE events ADD COLUMN owner_id varchar(36) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_events_owner_id ON events (owner_id);
@@ -0,0 +1,16 @@
+CREATE TABLE user_identities (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    user_id    varchar(36) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    issuer     varchar(255) NOT NULL,
+    subject    varchar(255) NOT NULL,
+    email      varchar(255),
+    created_at datetime
+);
+
+CREATE UNIQUE INDEX idx_user_identities_issuer_subject ON user_identities (issuer, subject);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+-- 匿名の参加者は NULL のまま。ログインしたユーザーは1イベントにつき1回だけ回答できる
+ALTER TABLE participants ADD COLUMN user_id varchar(36) REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_participants_event_user ON participants (event_id, user_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_events_workspace_id;
+ALTER TABLE events DROP COLUMN workspace_id;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
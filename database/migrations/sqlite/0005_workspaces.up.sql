@@ -0,0 +1,21 @@
+CREATE TABLE workspaces (
+    id         varchar(36) PRIMARY KEY,
+    name       varchar(100) NOT NULL,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE workspace_members (
+    workspace_id varchar(36) NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+    user_id      varchar(36) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role         varchar(20) NOT NULL,
+    created_at   datetime,
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX idx_workspace_members_user_id ON workspace_members (user_id);
+
+-- ワークスペースを削除する場合はイベントも削除する（アプリケーション側で子テーブルから削除する）
+ALTER TABLE events ADD COLUMN workspace_id varchar(36) REFERENCES workspaces (id);
+
+CREATE INDEX idx_events_workspace_id ON events (workspace_id);
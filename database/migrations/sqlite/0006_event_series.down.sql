@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_series_id;
+ALTER TABLE events DROP COLUMN series_id;
+DROP TABLE IF EXISTS event_series;
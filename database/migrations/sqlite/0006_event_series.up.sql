@@ -0,0 +1,30 @@
+CREATE TABLE event_series (
+    id                      varchar(36) PRIMARY KEY,
+    title                   varchar(255) NOT NULL,
+    description             text,
+    creator_name            varchar(100),
+    owner_id                varchar(36) REFERENCES users (id) ON DELETE SET NULL,
+    workspace_id            varchar(36) REFERENCES workspaces (id),
+    schedule                varchar(100) NOT NULL,
+    candidate_rule          text NOT NULL,
+    enabled                 numeric NOT NULL DEFAULT true,
+    next_run_at             datetime NOT NULL,
+    last_run_at             datetime,
+    allow_setting_changes   numeric DEFAULT true,
+    deadline_enable         numeric DEFAULT false,
+    deadline_days_before    integer DEFAULT 0,
+    auto_decision_enable    numeric DEFAULT false,
+    auto_decision_threshold integer DEFAULT 0,
+    rss_enabled             numeric DEFAULT false,
+    created_at              datetime,
+    updated_at              datetime
+);
+
+CREATE INDEX idx_event_series_owner_id ON event_series (owner_id);
+CREATE INDEX idx_event_series_workspace_id ON event_series (workspace_id);
+CREATE INDEX idx_event_series_next_run_at ON event_series (next_run_at);
+
+-- シリーズを削除しても作成済みのイベントは残す
+ALTER TABLE events ADD COLUMN series_id varchar(36) REFERENCES event_series (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_events_series_id ON events (series_id);
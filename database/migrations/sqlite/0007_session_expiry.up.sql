@@ -0,0 +1,2 @@
+-- 期限切れのセッションを定期的に削除する
+CREATE INDEX idx_sessions_expires_at ON sessions (expires_at);
@@ -0,0 +1,8 @@
+package docs
+
+import _ "embed"
+
+// OpenAPI は /api/v1/openapi.json で配信する API 仕様
+//
+//go:embed openapi.json
+var OpenAPI []byte
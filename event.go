@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"yotei-backend/handlers"
+	"yotei-backend/models"
+)
+
+// yotei-backend event show <id> / event export <id> / event import [-preserve-ids] <file>
+func runEvent(args []string) error {
+	flags := flag.NewFlagSet("event", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: yotei-backend event [show|export] <id>")
+		fmt.Fprintln(flags.Output(), "       yotei-backend event import [-preserve-ids] [-owner <email>] [-workspace <id>] <file|->")
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected a subcommand")
+	}
+	if args[0] == "import" {
+		return runEventImport(args[1:])
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("expected a subcommand and an event ID")
+	}
+	subcommand, eventID := flags.Arg(0), flags.Arg(1)
+
+	h, s, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch subcommand {
+	case "show":
+		event, err := s.GetEventDetails(ctx, eventID)
+		if err != nil {
+			return fmt.Errorf("failed to get event %s: %w", eventID, err)
+		}
+		return printEvent(event)
+	case "export":
+		export, err := h.BuildEventExport(ctx, eventID)
+		if err != nil {
+			return fmt.Errorf("failed to export event %s: %w", eventID, err)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown event command: %s", subcommand)
+	}
+}
+
+// event export の出力からイベントを作成する。"-" の場合は標準入力から読む
+func runEventImport(args []string) error {
+	flags := flag.NewFlagSet("event import", flag.ExitOnError)
+	preserveIDs := flags.Bool("preserve-ids", false, "keep the exported IDs instead of assigning new ones")
+	owner := flags.String("owner", "", "email address of the user who will own the imported event")
+	workspace := flags.String("workspace", "", "ID of the workspace to import the event into")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected a file to import")
+	}
+
+	input := os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+	var export handlers.EventExport
+	if err := json.NewDecoder(input).Decode(&export); err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+
+	h, s, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	opts := handlers.ImportOptions{PreserveIDs: *preserveIDs}
+	if *owner != "" {
+		user, err := s.GetUserByEmail(ctx, *owner)
+		if err != nil {
+			return fmt.Errorf("failed to get user %s: %w", *owner, err)
+		}
+		opts.OwnerID = &user.ID
+	}
+	if *workspace != "" {
+		if _, err := s.GetWorkspace(ctx, *workspace); err != nil {
+			return fmt.Errorf("failed to get workspace %s: %w", *workspace, err)
+		}
+		opts.WorkspaceID = workspace
+	}
+
+	event, err := h.RestoreEventExport(ctx, &export, opts)
+	if err != nil {
+		return fmt.Errorf("failed to import event: %w", err)
+	}
+	fmt.Printf("Imported event %s\n", event.ID)
+	return nil
+}
+
+func printEvent(event *models.Event) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "ID:\t%s\n", event.ID)
+	fmt.Fprintf(w, "Title:\t%s\n", event.Title)
+	fmt.Fprintf(w, "Creator:\t%s\n", event.CreatorName)
+	fmt.Fprintf(w, "Created:\t%s\n", event.CreatedAt.Local().Format("2006-01-02 15:04"))
+	if event.DeadlineEnable && event.Deadline != nil {
+		fmt.Fprintf(w, "Deadline:\t%s (reached: %t)\n", event.Deadline.Local().Format("2006-01-02 15:04"), event.DeadlineReached)
+	}
+	if event.AutoDecisionEnable {
+		fmt.Fprintf(w, "Auto decision:\t%d participants (reached: %t)\n", event.AutoDecisionThreshold, event.AutoDecisionReached)
+	}
+	fmt.Fprintf(w, "Participants:\t%d\n", len(event.Participants))
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "CANDIDATE DATE\tID\tAVAILABLE\tMAYBE\tUNAVAILABLE")
+	for _, candidateDate := range event.CandidateDates {
+		counts := map[string]int{}
+		for _, response := range candidateDate.Responses {
+			counts[response.Status]++
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n",
+			candidateDate.DateTime.Local().Format("2006-01-02 15:04"), candidateDate.ID,
+			counts["available"], counts["maybe"], counts["unavailable"])
+	}
+
+	return w.Flush()
+}
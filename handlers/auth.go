@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"yotei-backend/models"
+	"yotei-backend/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionCookieName = "yotei_session"
+	userLocalKey      = "user"
+	sessionLocalKey   = "session"
+)
+
+type SignUpRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+	Name     string `json:"name" validate:"required,max=100"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// AuthResponse のトークンは Cookie を使わないクライアント向け。Authorization: Bearer <token> で送る
+type AuthResponse struct {
+	User      *models.User `json:"user"`
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// 存在しないメールアドレスでも同じだけ時間をかけ、登録の有無を推測されないようにする
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// hashToken はセッションの保存に使うトークンのハッシュ。データベースが漏れてもトークンは分からない
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *Handler) SignUp(c *fiber.Ctx) error {
+	var req SignUpRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	req.Email = normalizeEmail(req.Email)
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	// bcrypt は72バイトまでしか扱えない
+	if len(req.Password) > 72 {
+		verr := &ValidationError{}
+		verr.Add("password", "must be at most 72 bytes")
+		return verr
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return internalError("Failed to hash password", err)
+	}
+
+	user := models.User{
+		ID:           uuid.New().String(),
+		Email:        req.Email,
+		Name:         req.Name,
+		PasswordHash: string(hash),
+	}
+	if err := h.store.CreateUser(c.UserContext(), &user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return ErrEmailTaken
+		}
+		return internalError("Failed to create user", err)
+	}
+
+	return h.startSession(c, fiber.StatusCreated, &user)
+}
+
+func (h *Handler) Login(c *fiber.Ctx) error {
+	var req LoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+
+	user, err := h.store.GetUserByEmail(c.UserContext(), normalizeEmail(req.Email))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return internalError("Failed to get user", err)
+	}
+	if user == nil || user.PasswordHash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+		return ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	return h.startSession(c, fiber.StatusOK, user)
+}
+
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	if session, ok := c.Locals(sessionLocalKey).(*models.Session); ok {
+		if err := h.store.DeleteSession(c.UserContext(), session.ID); err != nil {
+			return internalError("Failed to delete session", err)
+		}
+	}
+	h.setSessionCookie(c, "", time.Unix(0, 0))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) Me(c *fiber.Ctx) error {
+	return c.JSON(currentUser(c))
+}
+
+// randomToken は URL に使える 32 バイトのランダムな文字列を返す
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// startSession はセッションを作成し、Cookie とレスポンスの両方でトークンを返す
+func (h *Handler) startSession(c *fiber.Ctx, status int, user *models.User) error {
+	token, session, err := h.createSession(c, user)
+	if err != nil {
+		return err
+	}
+	return c.Status(status).JSON(AuthResponse{User: user, Token: token, ExpiresAt: session.ExpiresAt})
+}
+
+// createSession はセッションを作成して Cookie に設定する
+func (h *Handler) createSession(c *fiber.Ctx, user *models.User) (string, *models.Session, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, internalError("Failed to generate session token", err)
+	}
+
+	session := models.Session{
+		ID:        hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(h.config.SessionTTL),
+	}
+	if err := h.store.CreateSession(c.UserContext(), &session); err != nil {
+		return "", nil, internalError("Failed to create session", err)
+	}
+
+	h.setSessionCookie(c, token, session.ExpiresAt)
+	return token, &session, nil
+}
+
+func (h *Handler) setSessionCookie(c *fiber.Ctx, token string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   h.config.CookieSecure,
+		SameSite: h.config.CookieSameSite,
+	})
+}
+
+// Authenticate は Authorization ヘッダまたは Cookie のセッショントークンからユーザーを読み込む。
+// トークンがない・無効な場合は匿名のまま次に進む
+func (h *Handler) Authenticate(c *fiber.Ctx) error {
+	token := c.Cookies(sessionCookieName)
+	fromCookie := token != ""
+	if bearer, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok {
+		token = strings.TrimSpace(bearer)
+		fromCookie = false
+	}
+	if token == "" {
+		return c.Next()
+	}
+
+	ctx := c.UserContext()
+	session, err := h.store.GetSession(ctx, hashToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Next()
+	}
+	if err != nil {
+		return internalError("Failed to get session", err)
+	}
+	if !session.ExpiresAt.After(time.Now()) {
+		if err := h.store.DeleteSession(ctx, session.ID); err != nil {
+			return internalError("Failed to delete session", err)
+		}
+		return c.Next()
+	}
+
+	user, err := h.store.GetUser(ctx, session.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return c.Next()
+	}
+	if err != nil {
+		return internalError("Failed to get user", err)
+	}
+
+	if fromCookie && !jsonOrEmpty(c) {
+		return ErrJSONRequired
+	}
+
+	c.Locals(userLocalKey, user)
+	c.Locals(sessionLocalKey, session)
+	return c.Next()
+}
+
+// jsonOrEmpty は安全なメソッドか、本文が JSON か Content-Type のない変更系のリクエストかどうか。
+// HTML フォームは application/json を送れず、別サイトからの JSON の送信は CORS のプリフライトで止まるので、
+// Cookie で認証するリクエストをこれに限れば別サイトのフォームからの CSRF を防げる
+func jsonOrEmpty(c *fiber.Ctx) bool {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+	mediaType, _, _ := strings.Cut(c.Get(fiber.HeaderContentType), ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == "" || mediaType == fiber.MIMEApplicationJSON
+}
+
+// DeleteExpiredSessions は再び使われないまま期限が切れたセッションを削除する
+func (h *Handler) DeleteExpiredSessions(ctx context.Context) error {
+	deleted, err := h.store.DeleteExpiredSessions(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("Failed to delete expired sessions: %w", err)
+	}
+	if deleted > 0 {
+		slog.InfoContext(ctx, "Deleted expired sessions", "sessions", deleted)
+	}
+	return nil
+}
+
+// RequireUser はログインしていないリクエストに 401 を返す。Authenticate の後に登録する
+func (h *Handler) RequireUser(c *fiber.Ctx) error {
+	if currentUser(c) == nil {
+		return ErrUnauthorized
+	}
+	return c.Next()
+}
+
+// currentUser はログイン中のユーザー。匿名の場合は nil
+func currentUser(c *fiber.Ctx) *models.User {
+	user, _ := c.Locals(userLocalKey).(*models.User)
+	return user
+}
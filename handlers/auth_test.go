@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+	"yotei-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sessionCookie はレスポンスで設定されたセッションの Cookie
+func sessionCookie(t *testing.T, resp *http.Response) *http.Cookie {
+	t.Helper()
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "yotei_session" {
+			return cookie
+		}
+	}
+	t.Fatal("response has no session cookie")
+	return nil
+}
+
+func TestSignUpAndLogin(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	signUp := map[string]string{"email": "Alice@Example.com", "password": "password1234", "name": "Alice"}
+	resp := ts.request(http.MethodPost, "/api/v1/auth/signup", signUp, nil)
+	cookie := sessionCookie(t, resp)
+	var auth handlers.AuthResponse
+	decodeJSON(t, resp, http.StatusCreated, &auth)
+	if auth.Token == "" || auth.User.Email != "alice@example.com" || cookie.Value != auth.Token || !cookie.HttpOnly {
+		t.Fatalf("signup = %+v, cookie = %+v", auth, cookie)
+	}
+
+	var me models.User
+	decodeJSON(t, ts.request(http.MethodGet, "/api/v1/me", nil, bearer(auth.Token)), http.StatusOK, &me)
+	if me.ID != auth.User.ID {
+		t.Errorf("me = %+v, want %s", me, auth.User.ID)
+	}
+
+	// 大文字小文字の違うメールアドレスも同じアカウント
+	signUp["email"] = "alice@example.com"
+	expectError(t, ts.request(http.MethodPost, "/api/v1/auth/signup", signUp, nil), http.StatusConflict, handlers.CodeEmailTaken)
+	signUp["password"] = "short"
+	expectError(t, ts.request(http.MethodPost, "/api/v1/auth/signup", signUp, nil), http.StatusBadRequest, handlers.CodeValidationFailed)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		status   int
+		code     string
+	}{
+		{"valid", "ALICE@example.com", "password1234", http.StatusOK, ""},
+		{"wrong password", "alice@example.com", "password12345", http.StatusUnauthorized, handlers.CodeInvalidCredentials},
+		{"unknown email", "bob@example.com", "password1234", http.StatusUnauthorized, handlers.CodeInvalidCredentials},
+		{"missing password", "alice@example.com", "", http.StatusBadRequest, handlers.CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := ts.request(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": tt.email, "password": tt.password}, nil)
+			if tt.code != "" {
+				expectError(t, resp, tt.status, tt.code)
+				return
+			}
+			var login handlers.AuthResponse
+			decodeJSON(t, resp, tt.status, &login)
+			if login.User.ID != auth.User.ID || login.Token == auth.Token {
+				t.Errorf("login = %+v", login)
+			}
+		})
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	cfg := testConfig()
+	// 作成した時点で期限が切れているセッション
+	cfg.SessionTTL = -time.Minute
+	ts := newTestServer(t, cfg)
+	token := ts.signUp("alice@example.com", "Alice")
+
+	expectError(t, ts.request(http.MethodGet, "/api/v1/me", nil, bearer(token)), http.StatusUnauthorized, handlers.CodeUnauthorized)
+	// 期限切れのセッションは使われた時点で削除する
+	if deleted, err := ts.store.DeleteExpiredSessions(t.Context(), time.Now()); err != nil || deleted != 0 {
+		t.Errorf("DeleteExpiredSessions = %d, %v; want the session already deleted", deleted, err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	token := ts.signUp("alice@example.com", "Alice")
+
+	resp := ts.request(http.MethodPost, "/api/v1/auth/logout", nil, bearer(token))
+	if cookie := sessionCookie(t, resp); cookie.Value != "" || cookie.Expires.After(time.Now()) {
+		t.Errorf("logout cookie = %+v, want it cleared", cookie)
+	}
+	decodeJSON(t, resp, http.StatusNoContent, nil)
+
+	expectError(t, ts.request(http.MethodGet, "/api/v1/me", nil, bearer(token)), http.StatusUnauthorized, handlers.CodeUnauthorized)
+	// ログインしていなくてもログアウトできる
+	decodeJSON(t, ts.request(http.MethodPost, "/api/v1/auth/logout", nil, nil), http.StatusNoContent, nil)
+}
+
+func TestCookieAuthRequiresJSON(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	token := ts.signUp("alice@example.com", "Alice")
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		cookie      bool
+		status      int
+	}{
+		{"form with cookie", http.MethodPost, "application/x-www-form-urlencoded", "name=Team", true, http.StatusUnsupportedMediaType},
+		{"text with cookie", http.MethodPost, "text/plain", `{"name":"Team"}`, true, http.StatusUnsupportedMediaType},
+		{"JSON with cookie", http.MethodPost, "application/json; charset=utf-8", `{"name":"Team"}`, true, http.StatusCreated},
+		{"GET with cookie", http.MethodGet, "", "", true, http.StatusOK},
+		// Authorization ヘッダはブラウザが自動で付けないので CSRF にならない
+		{"form with bearer token", http.MethodPost, "application/x-www-form-urlencoded", "name=Team", false, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/workspaces", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set(fiber.HeaderContentType, tt.contentType)
+			}
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: "yotei_session", Value: token})
+			} else {
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+			}
+			resp, err := ts.app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if tt.status == http.StatusUnsupportedMediaType {
+				expectError(t, resp, tt.status, handlers.CodeUnsupportedMedia)
+				return
+			}
+			decodeJSON(t, resp, tt.status, nil)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yotei-backend/metrics"
+	"yotei-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type CloneEventRequest struct {
+	// 候補日と締切をずらす量。"7d" のような日数か、"-90m" のような time.ParseDuration の形式
+	Offset string `json:"offset"`
+	// 省略した場合は元のイベントのタイトル
+	Title string `json:"title" validate:"max=255"`
+}
+
+// eventOffset は日数と時間に分けて保持し、日数はタイムゾーンの暦日でずらす
+type eventOffset struct {
+	days     int
+	duration time.Duration
+}
+
+func parseEventOffset(value string) (eventOffset, error) {
+	if value == "" {
+		return eventOffset{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return eventOffset{}, fmt.Errorf("invalid offset: %s", value)
+		}
+		return eventOffset{days: n}, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return eventOffset{}, fmt.Errorf("invalid offset: %s", value)
+	}
+	return eventOffset{duration: d}, nil
+}
+
+// apply は夏時間のあるタイムゾーンでも日数分のずれが同じ時刻になるよう、loc の暦日でずらす
+func (o eventOffset) apply(t time.Time, loc *time.Location) time.Time {
+	return t.In(loc).AddDate(0, 0, o.days).Add(o.duration)
+}
+
+// CloneEvent はタイトル・説明・設定・候補日をコピーした新しいイベントを作成する。参加者と回答はコピーしない
+func (h *Handler) CloneEvent(c *fiber.Ctx) error {
+	var req CloneEventRequest
+	// ボディは省略できる
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return ErrInvalidRequest
+		}
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	offset, err := parseEventOffset(req.Offset)
+	if err != nil {
+		verr := &ValidationError{}
+		verr.Add("offset", "must be a number of days such as 7d or a duration such as 36h")
+		return verr
+	}
+
+	ctx := c.UserContext()
+	source, err := h.store.GetEvent(ctx, c.Params("id"))
+	if err != nil {
+		return eventLookupError(err)
+	}
+	if err := h.authorizeEvent(c, source); err != nil {
+		return err
+	}
+	// ワークスペースのイベントはワークスペースに作成するので、作成できるロールが必要
+	if source.WorkspaceID != nil {
+		if _, err := h.workspaceMember(c, *source.WorkspaceID, models.WorkspaceRoleOwner, models.WorkspaceRoleOrganizer); err != nil {
+			return err
+		}
+	}
+
+	candidateDates, err := h.store.ListCandidateDates(ctx, source.ID)
+	if err != nil {
+		return internalError("Failed to get candidate dates", err)
+	}
+	// 元のイベントの作成後に上限を下げた場合
+	if err := h.validateCandidateDateCount("candidate_dates", len(candidateDates)); err != nil {
+		return err
+	}
+
+	event := models.Event{
+		ID:                    uuid.New().String(),
+		Title:                 source.Title,
+		Description:           source.Description,
+		CreatorName:           source.CreatorName,
+		WorkspaceID:           source.WorkspaceID,
+		AllowSettingChanges:   source.AllowSettingChanges,
+		DeadlineEnable:        source.DeadlineEnable,
+		AutoDecisionEnable:    source.AutoDecisionEnable,
+		AutoDecisionThreshold: source.AutoDecisionThreshold,
+		RSSEnabled:            source.RSSEnabled,
+	}
+	if req.Title != "" {
+		event.Title = req.Title
+	}
+	for _, candidateDate := range candidateDates {
+		event.CandidateDates = append(event.CandidateDates, models.CandidateDate{
+			EventID:  event.ID,
+			DateTime: offset.apply(candidateDate.DateTime, h.location()),
+		})
+	}
+	if source.Deadline != nil {
+		deadline := offset.apply(*source.Deadline, h.location())
+		if source.DeadlineEnable && !deadline.After(time.Now()) {
+			verr := &ValidationError{}
+			verr.Add("offset", "must move the deadline into the future")
+			return verr
+		}
+		event.Deadline = &deadline
+	}
+	// ログインしている場合はコピーしたユーザーが主催者になる
+	if user := currentUser(c); user != nil {
+		event.OwnerID = &user.ID
+		event.CreatorName = user.Name
+	}
+
+	if err := h.store.CreateEvent(ctx, &event); err != nil {
+		return internalError("Failed to create event", err)
+	}
+	metrics.EventsCreated.Inc()
+
+	return c.Status(fiber.StatusCreated).JSON(CreateEventResponse{ID: event.ID})
+}
@@ -0,0 +1,99 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+)
+
+func TestCloneEvent(t *testing.T) {
+	cfg := testConfig()
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("load time zone: %v", err)
+	}
+	cfg.TimeZone = newYork
+	ts := newTestServer(t, cfg)
+
+	// 2030-03-10 に夏時間が始まる
+	first := time.Date(2030, 3, 5, 10, 0, 0, 0, newYork)
+	eventID := ts.createEvent(eventRequest("Weekly sync", first), nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "Alice", ids, nil), nil), http.StatusCreated, nil)
+	path := "/api/v1/events/" + eventID + "/clone"
+
+	tests := []struct {
+		offset string
+		want   time.Time
+	}{
+		// 日数は暦日でずらすので夏時間をまたいでも同じ時刻になる
+		{"7d", time.Date(2030, 3, 12, 10, 0, 0, 0, newYork)},
+		{"168h", time.Date(2030, 3, 12, 11, 0, 0, 0, newYork)},
+		{"-90m", time.Date(2030, 3, 5, 8, 30, 0, 0, newYork)},
+		{"", first},
+	}
+	for _, tt := range tests {
+		var created handlers.CreateEventResponse
+		decodeJSON(t, ts.request(http.MethodPost, path, map[string]string{"offset": tt.offset}, nil), http.StatusCreated, &created)
+		clone := ts.getEvent(created.ID, nil)
+		if len(clone.CandidateDates) != 1 || !clone.CandidateDates[0].DateTime.Equal(tt.want) {
+			t.Errorf("offset %q: candidate dates = %+v, want %s", tt.offset, clone.CandidateDates, tt.want)
+		}
+		if clone.Title != "Weekly sync" || len(clone.Participants) != 0 {
+			t.Errorf("offset %q: clone = %+v, want the same title without participants", tt.offset, clone)
+		}
+	}
+
+	// ボディは省略でき、ログインしているとコピーしたユーザーが主催者になる
+	token := ts.signUp("bob@example.com", "Bob")
+	var created handlers.CreateEventResponse
+	decodeJSON(t, ts.request(http.MethodPost, path, nil, bearer(token)), http.StatusCreated, &created)
+	if clone := ts.getEvent(created.ID, bearer(token)); clone.CreatorName != "Bob" || clone.OwnerID == nil {
+		t.Errorf("clone = %+v, want Bob as the owner", clone)
+	}
+	decodeJSON(t, ts.request(http.MethodPost, path, map[string]string{"title": "Next sync"}, nil), http.StatusCreated, &created)
+	if clone := ts.getEvent(created.ID, nil); clone.Title != "Next sync" {
+		t.Errorf("title = %q, want Next sync", clone.Title)
+	}
+
+	for _, offset := range []string{"7x", "d", "1.5d"} {
+		expectError(t, ts.request(http.MethodPost, path, map[string]string{"offset": offset}, nil), http.StatusBadRequest, handlers.CodeValidationFailed)
+	}
+	expectError(t, ts.request(http.MethodPost, "/api/v1/events/00000000-0000-0000-0000-000000000000/clone", nil, nil), http.StatusNotFound, handlers.CodeEventNotFound)
+
+	// 締切が過去のままになるコピーは作成しない
+	pastID := ts.createEventWithDeadline(time.Now().Add(-time.Hour), first)
+	pastPath := "/api/v1/events/" + pastID + "/clone"
+	apiErr := expectError(t, ts.request(http.MethodPost, pastPath, nil, nil), http.StatusBadRequest, handlers.CodeValidationFailed)
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "offset" {
+		t.Errorf("details = %+v, want offset", apiErr.Details)
+	}
+	decodeJSON(t, ts.request(http.MethodPost, pastPath, map[string]string{"offset": "7d"}, nil), http.StatusCreated, &created)
+	if clone := ts.getEvent(created.ID, nil); clone.Deadline == nil || !clone.Deadline.After(time.Now()) {
+		t.Errorf("deadline = %v, want a future deadline", clone.Deadline)
+	}
+}
+
+func TestCloneWorkspaceEvent(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	owner := ts.signUp("owner@example.com", "Owner")
+	member := ts.signUp("member@example.com", "Member")
+	outsider := ts.signUp("outsider@example.com", "Outsider")
+	workspaceID := ts.createWorkspace(owner, "Team")
+	ts.addMember(owner, workspaceID, "member@example.com", "member")
+
+	req := eventRequest("Workspace event", time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC))
+	req["workspace_id"] = workspaceID
+	path := "/api/v1/events/" + ts.createEvent(req, bearer(owner)) + "/clone"
+
+	// コピーはワークスペースに作成されるので、イベントを作成できる役割が必要
+	expectError(t, ts.request(http.MethodPost, path, nil, bearer(outsider)), http.StatusNotFound, handlers.CodeEventNotFound)
+	expectError(t, ts.request(http.MethodPost, path, nil, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+	var created handlers.CreateEventResponse
+	decodeJSON(t, ts.request(http.MethodPost, path, nil, bearer(owner)), http.StatusCreated, &created)
+	if clone := ts.getEvent(created.ID, bearer(owner)); clone.WorkspaceID == nil || *clone.WorkspaceID != workspaceID {
+		t.Errorf("workspace_id = %v, want %s", clone.WorkspaceID, workspaceID)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"time"
+
+	"yotei-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DashboardEvent はダッシュボードに表示するイベントの概要
+type DashboardEvent struct {
+	ID               string     `json:"id"`
+	Title            string     `json:"title"`
+	Status           string     `json:"status"`
+	ParticipantCount int64      `json:"participant_count"`
+	DeadlineEnable   bool       `json:"deadline_enable"`
+	Deadline         *time.Time `json:"deadline"`
+	VoteURL          string     `json:"vote_url"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// eventStatus は締切または自動決定で日程が決まっていれば decided、そうでなければ open
+func eventStatus(event *models.Event) string {
+	if event.DeadlineReached || event.AutoDecisionReached {
+		return "decided"
+	}
+	return "open"
+}
+
+func isOwner(c *fiber.Ctx, event *models.Event) bool {
+	user := currentUser(c)
+	return user != nil && event.OwnerID != nil && *event.OwnerID == user.ID
+}
+
+// ListMyEvents はログイン中のユーザーが作成したイベントを新しい順に返す
+func (h *Handler) ListMyEvents(c *fiber.Ctx) error {
+	events, err := h.store.ListEventsByOwner(c.UserContext(), currentUser(c).ID)
+	if err != nil {
+		return internalError("Failed to get events", err)
+	}
+	return h.sendDashboardEvents(c, events)
+}
+
+// ListMyParticipations はログイン中のユーザーが回答したイベントを回答の新しい順に返す
+func (h *Handler) ListMyParticipations(c *fiber.Ctx) error {
+	events, err := h.store.ListEventsByParticipant(c.UserContext(), currentUser(c).ID)
+	if err != nil {
+		return internalError("Failed to get events", err)
+	}
+	return h.sendDashboardEvents(c, events)
+}
+
+func (h *Handler) sendDashboardEvents(c *fiber.Ctx, events []models.Event) error {
+	response, err := h.dashboardEvents(c, events)
+	if err != nil {
+		return err
+	}
+	return c.JSON(response)
+}
+
+func (h *Handler) dashboardEvents(c *fiber.Ctx, events []models.Event) ([]DashboardEvent, error) {
+	eventIDs := make([]string, len(events))
+	for i, event := range events {
+		eventIDs[i] = event.ID
+	}
+	participantCounts, err := h.store.CountParticipantsByEvent(c.UserContext(), eventIDs)
+	if err != nil {
+		return nil, internalError("Failed to count participants", err)
+	}
+
+	response := make([]DashboardEvent, len(events))
+	for i, event := range events {
+		response[i] = DashboardEvent{
+			ID:               event.ID,
+			Title:            event.Title,
+			Status:           eventStatus(&event),
+			ParticipantCount: participantCounts[event.ID],
+			DeadlineEnable:   event.DeadlineEnable,
+			Deadline:         event.Deadline,
+			VoteURL:          h.voteURL(event.ID),
+			CreatedAt:        event.CreatedAt,
+		}
+	}
+	return response, nil
+}
+
+// DeleteEvent はイベントを関連データごと削除する。主催者とワークスペースのオーナー・オーガナイザーのみ実行できる
+func (h *Handler) DeleteEvent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	event, err := h.store.GetEvent(ctx, c.Params("id"))
+	if err != nil {
+		return eventLookupError(err)
+	}
+	// メンバー以外にはワークスペースのイベントの存在を明かさないよう、権限の確認より先に 404 を返す
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
+	}
+	canManage, err := h.canManageEvent(c, event)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return ErrNotEventOwner
+	}
+
+	if err := h.store.DeleteEvent(ctx, event.ID); err != nil {
+		return eventLookupError(err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
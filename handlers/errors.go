@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"yotei-backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeNotFound           = "not_found"
+	CodeEventNotFound      = "event_not_found"
+	CodeSettingsLocked     = "settings_locked"
+	CodeParticipantExists  = "participant_exists"
+	CodeEventFull          = "event_full"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeEmailTaken         = "email_taken"
+	CodeOIDCFailed         = "oidc_failed"
+	CodeWorkspaceNotFound  = "workspace_not_found"
+	CodeUserNotFound       = "user_not_found"
+	CodeMemberExists       = "member_exists"
+	CodeLastOwner          = "last_owner"
+	CodeSeriesNotFound     = "series_not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodePayloadTooLarge    = "payload_too_large"
+	CodeUnsupportedMedia   = "unsupported_media_type"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeInternal           = "internal_error"
+)
+
+// APIError はクライアントに返すエラー。Code でフロントエンドが分岐できるようにする
+type APIError struct {
+	Status    int          `json:"-"`
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+
+	// ログ用の元のエラー。レスポンスには含めない
+	Err error `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+type ErrorResponse struct {
+	Error *APIError `json:"error"`
+}
+
+var (
+	ErrInvalidRequest    = &APIError{Status: fiber.StatusBadRequest, Code: CodeInvalidRequest, Message: "Invalid request format"}
+	ErrEventNotFound     = &APIError{Status: fiber.StatusNotFound, Code: CodeEventNotFound, Message: "Event not found"}
+	ErrSettingsLocked    = &APIError{Status: fiber.StatusForbidden, Code: CodeSettingsLocked, Message: "This event's settings cannot be changed"}
+	ErrParticipantExists = &APIError{Status: fiber.StatusConflict, Code: CodeParticipantExists, Message: "Participant already exists"}
+	ErrEventFull         = &APIError{Status: fiber.StatusConflict, Code: CodeEventFull, Message: "This event has reached the maximum number of participants"}
+	ErrUnauthorized      = &APIError{Status: fiber.StatusUnauthorized, Code: CodeUnauthorized, Message: "Login required"}
+	ErrNotEventOwner     = &APIError{Status: fiber.StatusForbidden, Code: CodeForbidden, Message: "Only the organizer of this event can do this"}
+	// メールアドレスとパスワードのどちらが違うかは返さない
+	ErrInvalidCredentials = &APIError{Status: fiber.StatusUnauthorized, Code: CodeInvalidCredentials, Message: "Invalid email or password"}
+	ErrEmailTaken         = &APIError{Status: fiber.StatusConflict, Code: CodeEmailTaken, Message: "Email address is already registered"}
+	ErrOIDCDisabled       = &APIError{Status: fiber.StatusNotFound, Code: CodeNotFound, Message: "OpenID Connect login is not configured"}
+	// メンバーでないワークスペースは存在も明かさない
+	ErrWorkspaceNotFound  = &APIError{Status: fiber.StatusNotFound, Code: CodeWorkspaceNotFound, Message: "Workspace not found"}
+	ErrWorkspaceForbidden = &APIError{Status: fiber.StatusForbidden, Code: CodeForbidden, Message: "Your role in this workspace does not allow this"}
+	ErrUserNotFound       = &APIError{Status: fiber.StatusNotFound, Code: CodeUserNotFound, Message: "No user is registered with this email address"}
+	ErrMemberNotFound     = &APIError{Status: fiber.StatusNotFound, Code: CodeNotFound, Message: "Member not found"}
+	ErrMemberExists       = &APIError{Status: fiber.StatusConflict, Code: CodeMemberExists, Message: "User is already a member of this workspace"}
+	ErrLastOwner          = &APIError{Status: fiber.StatusConflict, Code: CodeLastOwner, Message: "A workspace must have at least one owner"}
+	ErrSeriesNotFound     = &APIError{Status: fiber.StatusNotFound, Code: CodeSeriesNotFound, Message: "Event series not found"}
+	// Cookie で認証した変更系のリクエストは JSON でなければならない（CSRF 対策）
+	ErrJSONRequired = &APIError{Status: fiber.StatusUnsupportedMediaType, Code: CodeUnsupportedMedia, Message: "Requests authenticated with the session cookie must use Content-Type: application/json"}
+)
+
+func internalError(message string, err error) *APIError {
+	return &APIError{Status: fiber.StatusInternalServerError, Code: CodeInternal, Message: message, Err: err}
+}
+
+// レコードが存在しない場合は notFound を、それ以外は内部エラーを返す
+func lookupError(err error, notFound *APIError, message string) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return notFound
+	}
+	return internalError(message, err)
+}
+
+func eventLookupError(err error) error {
+	return lookupError(err, ErrEventNotFound, "Failed to get event")
+}
+
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		copied := *apiErr
+		return &copied
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return &APIError{
+			Status:  fiber.StatusBadRequest,
+			Code:    CodeValidationFailed,
+			Message: "Validation failed",
+			Details: verr.Fields,
+		}
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return &APIError{
+			Status:  fiberErr.Code,
+			Code:    codeForStatus(fiberErr.Code),
+			Message: fiberErr.Message,
+		}
+	}
+
+	if errors.Is(err, store.ErrNotFound) {
+		return &APIError{Status: fiber.StatusNotFound, Code: CodeNotFound, Message: "Resource not found", Err: err}
+	}
+
+	return internalError("Internal server error", err)
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return CodeInvalidRequest
+	case fiber.StatusUnauthorized:
+		return CodeUnauthorized
+	case fiber.StatusForbidden:
+		return CodeForbidden
+	case fiber.StatusNotFound:
+		return CodeNotFound
+	case fiber.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case fiber.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case fiber.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case fiber.StatusTooManyRequests:
+		return CodeTooManyRequests
+	}
+	if status >= fiber.StatusInternalServerError {
+		return CodeInternal
+	}
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
+// ErrorHandler は fiber.Config.ErrorHandler に設定する共通エラーハンドラ
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	apiErr := toAPIError(err)
+	apiErr.RequestID = c.GetRespHeader(fiber.HeaderXRequestID)
+
+	if apiErr.Status >= fiber.StatusInternalServerError {
+		slog.ErrorContext(c.UserContext(), "Request failed", "error", err)
+	}
+
+	return c.Status(apiErr.Status).JSON(ErrorResponse{Error: apiErr})
+}
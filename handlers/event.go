@@ -1,19 +1,60 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"strings"
 	"time"
 
-	"yotei-backend/database"
+	"yotei-backend/metrics"
 	"yotei-backend/models"
+	"yotei-backend/store"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/gorilla/feeds"
 )
 
+type Config struct {
+	// RSS のリンク先に使うフロントエンドのURL
+	FrontendURL string
+	// CSV などで日時を表示するタイムゾーン。nil の場合は UTC
+	TimeZone *time.Location
+	// 1イベントに登録できる参加者数の上限。0 は無制限
+	MaxParticipantsPerEvent int
+	// 1イベントの候補日数の上限。0 は無制限
+	MaxCandidateDatesPerEvent int
+
+	// ログインセッション
+	SessionTTL     time.Duration
+	CookieSecure   bool
+	CookieSameSite string
+
+	// OpenID Connect でのログイン。nil の場合は無効
+	OIDC OIDCProvider
+}
+
+type Handler struct {
+	store  store.Store
+	config Config
+}
+
+func New(s store.Store, cfg Config) *Handler {
+	return &Handler{store: s, config: cfg}
+}
+
+// location は日時の表示や暦日の計算に使うタイムゾーン
+func (h *Handler) location() *time.Location {
+	if h.config.TimeZone == nil {
+		return time.UTC
+	}
+	return h.config.TimeZone
+}
+
+func (h *Handler) voteURL(eventID string) string {
+	return fmt.Sprintf("%s/%s/vote", strings.TrimSuffix(h.config.FrontendURL, "/"), eventID)
+}
+
 type EventSettingsRequest struct {
 	AllowSettingChanges   bool   `json:"allow_setting_changes"`
 	DeadlineEnable        bool   `json:"deadline_enable"`
@@ -24,10 +65,12 @@ type EventSettingsRequest struct {
 }
 
 type CreateEventRequest struct {
-	Title          string               `json:"title" validate:"required"`
-	Description    string               `json:"description"`
-	CreatorName    string               `json:"creator_name"`
-	CandidateDates []string             `json:"candidate_dates" validate:"required,min=1"` // ISO 8601形式の日時文字列の配列
+	Title       string `json:"title" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=10000"`
+	CreatorName string `json:"creator_name" validate:"max=100"`
+	// 指定した場合はワークスペースのメンバーにだけ公開する。オーナーかオーガナイザーのみ指定できる
+	WorkspaceID    string               `json:"workspace_id" validate:"omitempty,uuid"`
+	CandidateDates []string             `json:"candidate_dates" validate:"required,min=1,unique,dive,rfc3339"` // ISO 8601形式の日時文字列の配列
 	Settings       EventSettingsRequest `json:"settings"`
 }
 
@@ -36,49 +79,93 @@ type CreateEventResponse struct {
 }
 
 type CandidateDateIDRequest struct {
-	ID uint `json:"id"`
+	ID uint `json:"id" validate:"required"`
 }
 
 type RegisterParticipantRequest struct {
-	EventID                   string                   `json:"event_id" validate:"required"`
-	ParticipantID             uint                     `json:"participant_id" validate:"required"`
-	Name                      string                   `json:"name" validate:"required"`
-	AvailableCandidateDates   []CandidateDateIDRequest `json:"available_candidate_dates" validate:"required"`
-	UnavailableCandidateDates []CandidateDateIDRequest `json:"unavailable_candidate_dates" validate:"required"`
+	EventID       string `json:"event_id" validate:"required"`
+	ParticipantID uint   `json:"participant_id" validate:"required"`
+	// ログインしている場合は省略でき、ユーザー名を使う
+	Name                      string                   `json:"name" validate:"max=100"`
+	AvailableCandidateDates   []CandidateDateIDRequest `json:"available_candidate_dates" validate:"required,unique=ID,dive"`
+	UnavailableCandidateDates []CandidateDateIDRequest `json:"unavailable_candidate_dates" validate:"required,unique=ID,dive"`
+}
+
+// 候補日IDがイベントに属しているか、参加可否の両方に含まれていないかを確認する
+// validateCandidateDateCount は候補日の数が MaxCandidateDatesPerEvent を超えていないか確認する
+func (h *Handler) validateCandidateDateCount(field string, count int) error {
+	if limit := h.config.MaxCandidateDatesPerEvent; limit > 0 && count > limit {
+		verr := &ValidationError{}
+		verr.Add(field, fmt.Sprintf("must contain at most %d item(s)", limit))
+		return verr
+	}
+	return nil
 }
 
-func CreateEvent(c *fiber.Ctx) error {
+func validateParticipantResponses(eventID string, candidateDates []models.CandidateDate, req RegisterParticipantRequest) error {
+	verr := &ValidationError{}
+
+	if req.EventID != eventID {
+		verr.Add("event_id", "must match the event in the URL")
+	}
+
+	owned := make(map[uint]bool, len(candidateDates))
+	for _, candidateDate := range candidateDates {
+		owned[candidateDate.ID] = true
+	}
+
+	available := make(map[uint]bool, len(req.AvailableCandidateDates))
+	for i, candidateDate := range req.AvailableCandidateDates {
+		if !owned[candidateDate.ID] {
+			verr.Add(fmt.Sprintf("available_candidate_dates[%d].id", i), "does not belong to this event")
+		}
+		available[candidateDate.ID] = true
+	}
+
+	for i, candidateDate := range req.UnavailableCandidateDates {
+		if !owned[candidateDate.ID] {
+			verr.Add(fmt.Sprintf("unavailable_candidate_dates[%d].id", i), "does not belong to this event")
+		}
+		if available[candidateDate.ID] {
+			verr.Add(fmt.Sprintf("unavailable_candidate_dates[%d].id", i), "is also listed as available")
+		}
+	}
+
+	return verr.OrNil()
+}
+
+func (h *Handler) CreateEvent(c *fiber.Ctx) error {
 	var req CreateEventRequest
 
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request format",
-		})
+		return ErrInvalidRequest
 	}
 
-	if req.Title == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Title is empty",
-		})
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	if err := h.validateCandidateDateCount("candidate_dates", len(req.CandidateDates)); err != nil {
+		return err
 	}
 
-	if len(req.CandidateDates) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "No candidate dates provided",
-		})
+	if req.WorkspaceID != "" {
+		if currentUser(c) == nil {
+			return ErrUnauthorized
+		}
+		member, err := h.workspaceMember(c, req.WorkspaceID)
+		if err != nil {
+			return err
+		}
+		if !member.CanManageEvents() {
+			return ErrWorkspaceForbidden
+		}
 	}
 
 	eventID := uuid.New().String()
 
 	var candidateDates []models.CandidateDate
 	for _, dateStr := range req.CandidateDates {
-		parsedTime, err := time.Parse(time.RFC3339, dateStr)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid candidate date format. Please use ISO 8601 format",
-			})
-		}
-
+		parsedTime, _ := time.Parse(time.RFC3339, dateStr)
 		candidateDates = append(candidateDates, models.CandidateDate{
 			EventID:  eventID,
 			DateTime: parsedTime,
@@ -86,12 +173,12 @@ func CreateEvent(c *fiber.Ctx) error {
 	}
 
 	var deadline *time.Time
-	if req.Settings.DeadlineEnable && req.Settings.Deadline != "" {
-		parsedDeadline, err := time.Parse(time.RFC3339, req.Settings.Deadline)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid deadline format. Please use ISO 8601 format",
-			})
+	if req.Settings.DeadlineEnable {
+		parsedDeadline, _ := time.Parse(time.RFC3339, req.Settings.Deadline)
+		if !parsedDeadline.After(time.Now()) {
+			verr := &ValidationError{}
+			verr.Add("settings.deadline", "must be in the future")
+			return verr
 		}
 		deadline = &parsedDeadline
 	}
@@ -109,12 +196,21 @@ func CreateEvent(c *fiber.Ctx) error {
 		RSSEnabled:            req.Settings.RSSEnabled,
 		CandidateDates:        candidateDates,
 	}
+	if req.WorkspaceID != "" {
+		event.WorkspaceID = &req.WorkspaceID
+	}
+	// ログインしている場合は主催者として紐づけ、ダッシュボードから管理できるようにする
+	if user := currentUser(c); user != nil {
+		event.OwnerID = &user.ID
+		if event.CreatorName == "" {
+			event.CreatorName = user.Name
+		}
+	}
 
-	if err := database.DB.Create(&event).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create event",
-		})
+	if err := h.store.CreateEvent(c.UserContext(), &event); err != nil {
+		return internalError("Failed to create event", err)
 	}
+	metrics.EventsCreated.Inc()
 
 	response := CreateEventResponse{
 		ID: event.ID,
@@ -123,39 +219,81 @@ func CreateEvent(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
-func GetEvent(c *fiber.Ctx) error {
+func (h *Handler) GetEvent(c *fiber.Ctx) error {
+	if c.Query("include") != "" || c.Query("fields") != "" {
+		return h.getEventView(c)
+	}
+
 	eventID := c.Params("id")
 
-	var event models.Event
-	if err := database.DB.
-		Preload("CandidateDates").
-		Preload("CandidateDates.Responses").
-		Preload("Participants").
-		Preload("Participants.Responses").
-		First(&event, "id = ?", eventID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Event not found",
-		})
+	event, err := h.store.GetEventDetails(c.UserContext(), eventID)
+	if err != nil {
+		return eventLookupError(err)
+	}
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
 	}
 
+	if !showsUserIDs(c, event) {
+		event.OwnerID = nil
+		for i := range event.Participants {
+			event.Participants[i].UserID = ownUserID(c, event.Participants[i].UserID)
+		}
+	}
 	return c.JSON(event)
 }
 
-func RegisterParticipant(c *fiber.Ctx) error {
+// showsUserIDs は作成者と回答者のユーザーIDを見せてよいか。ワークスペースのイベントは authorizeEvent で
+// メンバーだけに見せているので見せる。誰でも参照できるイベントでは作成者にだけ見せる
+func showsUserIDs(c *fiber.Ctx, event *models.Event) bool {
+	return event.WorkspaceID != nil || isOwner(c, event)
+}
+
+// ownUserID は userID がログイン中のユーザーの場合だけ返す。自分の回答を見分けられるようにする
+func ownUserID(c *fiber.Ctx, userID *string) *string {
+	if user := currentUser(c); user != nil && userID != nil && *userID == user.ID {
+		return userID
+	}
+	return nil
+}
+
+func (h *Handler) RegisterParticipant(c *fiber.Ctx) error {
 	eventID := c.Params("id")
 	var req RegisterParticipantRequest
 
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request format",
-		})
+		return ErrInvalidRequest
 	}
 
-	var event models.Event
-	if err := database.DB.Preload("Participants").First(&event, "id = ?", eventID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Event not found",
-		})
+	user := currentUser(c)
+	if user != nil && req.Name == "" {
+		req.Name = user.Name
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	if req.Name == "" {
+		verr := &ValidationError{}
+		verr.Add("name", "is required")
+		return verr
+	}
+
+	ctx := c.UserContext()
+	event, err := h.store.GetEvent(ctx, eventID)
+	if err != nil {
+		return eventLookupError(err)
+	}
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
+	}
+
+	candidateDates, err := h.store.ListCandidateDates(ctx, eventID)
+	if err != nil {
+		return internalError("Failed to get candidate dates", err)
+	}
+
+	if err := validateParticipantResponses(event.ID, candidateDates, req); err != nil {
+		return err
 	}
 
 	var responses []models.Response
@@ -181,57 +319,77 @@ func RegisterParticipant(c *fiber.Ctx) error {
 		Name:      req.Name,
 		Responses: responses,
 	}
+	// ログインしている場合は回答をユーザーに紐づけ、イベントをまたいで参照できるようにする
+	if user != nil {
+		participant.UserID = &user.ID
+	}
 
-	if err := database.DB.Create(&participant).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to register participant",
-		})
+	if err := h.store.CreateParticipant(ctx, &participant, h.config.MaxParticipantsPerEvent); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			return ErrParticipantExists
+		case errors.Is(err, store.ErrLimitReached):
+			return ErrEventFull
+		}
+		return lookupError(err, ErrEventNotFound, "Failed to register participant")
+	}
+	metrics.ParticipantsRegistered.Inc()
+
+	participantCount, err := h.store.CountParticipants(ctx, eventID)
+	if err != nil {
+		return internalError("Failed to count participants", err)
 	}
 
-	log.Println("Participants:", len(event.Participants)+1)
-	log.Println("AutoDecisionThreshold:", event.AutoDecisionThreshold)
-	log.Println("AutoDecisionEnable:", event.AutoDecisionEnable)
-	if event.AutoDecisionEnable && len(event.Participants)+1 >= event.AutoDecisionThreshold {
-		if err := CheckAutoDecisionAndFinalize(eventID); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to check and finalize auto decision",
-			})
+	if event.AutoDecisionEnable && participantCount >= int64(event.AutoDecisionThreshold) {
+		if err := h.CheckAutoDecisionAndFinalize(ctx, eventID); err != nil {
+			return internalError("Failed to check and finalize auto decision", err)
 		}
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(participant)
 }
 
-func UpdateEventSettings(c *fiber.Ctx) error {
+func (h *Handler) UpdateEventSettings(c *fiber.Ctx) error {
 	eventID := c.Params("id")
 	var req EventSettingsRequest
 
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request format",
-		})
+		return ErrInvalidRequest
 	}
 
-	var event models.Event
-	if err := database.DB.First(&event, "id = ?", eventID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Event not found",
-		})
+	if err := validateStruct(req); err != nil {
+		return err
 	}
 
+	event, err := h.store.GetEvent(c.UserContext(), eventID)
+	if err != nil {
+		return eventLookupError(err)
+	}
+
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
+	}
+
+	// 主催者は設定を変更不可にしたイベントでも変更できる
 	if !event.AllowSettingChanges {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "This event's settings cannot be changed",
-		})
+		canManage, err := h.canManageEvent(c, event)
+		if err != nil {
+			return err
+		}
+		if !canManage {
+			return ErrSettingsLocked
+		}
 	}
 
 	var deadline *time.Time
-	if req.DeadlineEnable && req.Deadline != "" {
-		parsedDeadline, err := time.Parse(time.RFC3339, req.Deadline)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid deadline format. Please use ISO 8601 format",
-			})
+	if req.DeadlineEnable {
+		parsedDeadline, _ := time.Parse(time.RFC3339, req.Deadline)
+		// 既存の締切をそのまま送り直す場合は過去の日時でも許可する
+		unchanged := event.Deadline != nil && event.Deadline.Equal(parsedDeadline)
+		if !unchanged && !parsedDeadline.After(time.Now()) {
+			verr := &ValidationError{}
+			verr.Add("deadline", "must be in the future")
+			return verr
 		}
 		deadline = &parsedDeadline
 	}
@@ -251,10 +409,8 @@ func UpdateEventSettings(c *fiber.Ctx) error {
 	event.AutoDecisionThreshold = req.AutoDecisionThreshold
 	event.RSSEnabled = req.RSSEnabled
 
-	if err := database.DB.Save(&event).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update settings",
-		})
+	if err := h.store.UpdateEvent(c.UserContext(), event); err != nil {
+		return internalError("Failed to update settings", err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -262,27 +418,26 @@ func UpdateEventSettings(c *fiber.Ctx) error {
 	})
 }
 
-func EventRSS(c *fiber.Ctx) error {
+func (h *Handler) EventRSS(c *fiber.Ctx) error {
 	eventID := c.Params("id")
-	var event models.Event
-	if err := database.DB.First(&event, "id = ?", eventID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Event not found",
-		})
+	event, err := h.store.GetEvent(c.UserContext(), eventID)
+	if err != nil {
+		return eventLookupError(err)
+	}
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
 	}
 
 	feed := &feeds.Feed{
 		Title:       fmt.Sprintf("%s", event.Title),
-		Link:        &feeds.Link{Href: fmt.Sprintf("%s/%d/vote", os.Getenv("FRONTEND_URL"), event.ID)}, // TODO: 本番環境のURLに変更
+		Link:        &feeds.Link{Href: h.voteURL(event.ID)},
 		Description: "このイベントの予定日が決定次第、通知が届きます。",
 		Created:     time.Now(), // (実際にはイベントの作成日時など)
 	}
 
-	var rssFeeds []models.RSSFeed
-	if err := database.DB.Where("event_id = ?", eventID).Find(&rssFeeds).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get RSS feeds",
-		})
+	rssFeeds, err := h.store.ListFeeds(c.UserContext(), eventID)
+	if err != nil {
+		return internalError("Failed to get RSS feeds", err)
 	}
 
 	for _, rssFeed := range rssFeeds {
@@ -296,9 +451,8 @@ func EventRSS(c *fiber.Ctx) error {
 
 	rss, err := feed.ToRss()
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate RSS feed",
-		})
+		return internalError("Failed to generate RSS feed", err)
 	}
+	c.Set(fiber.HeaderContentType, "application/rss+xml; charset=utf-8")
 	return c.Status(fiber.StatusOK).SendString(rss)
 }
@@ -0,0 +1,483 @@
+package handlers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+	"yotei-backend/models"
+)
+
+// candidateDateIDs は候補日の ID を日時の順に返す
+func candidateDateIDs(t *testing.T, event models.Event) []uint {
+	t.Helper()
+
+	candidateDates := slices.Clone(event.CandidateDates)
+	slices.SortFunc(candidateDates, func(a, b models.CandidateDate) int { return a.DateTime.Compare(b.DateTime) })
+	ids := make([]uint, len(candidateDates))
+	for i, candidateDate := range candidateDates {
+		ids[i] = candidateDate.ID
+	}
+	return ids
+}
+
+func TestCreateAndGetEvent(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 1)
+	eventID := ts.createEvent(eventRequest("Team lunch", first, second), nil)
+
+	event := ts.getEvent(eventID, nil)
+	if event.ID != eventID || event.Title != "Team lunch" || event.CreatorName != "Organizer" {
+		t.Fatalf("event = %+v", event)
+	}
+	if event.OwnerID != nil {
+		t.Errorf("anonymous event has owner %q", *event.OwnerID)
+	}
+	if !event.AllowSettingChanges {
+		t.Error("allow_setting_changes = false, want true")
+	}
+	if len(event.CandidateDates) != 2 {
+		t.Fatalf("candidate dates = %d, want 2", len(event.CandidateDates))
+	}
+	for _, candidateDate := range event.CandidateDates {
+		if !candidateDate.DateTime.Equal(first) && !candidateDate.DateTime.Equal(second) {
+			t.Errorf("unexpected candidate date %s", candidateDate.DateTime)
+		}
+	}
+	if len(event.Participants) != 0 {
+		t.Errorf("participants = %d, want 0", len(event.Participants))
+	}
+}
+
+func TestCreateEventWithLogin(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	token := ts.signUp("owner@example.com", "Owner")
+
+	req := eventRequest("Planning", time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC))
+	delete(req, "creator_name")
+	eventID := ts.createEvent(req, bearer(token))
+
+	event := ts.getEvent(eventID, bearer(token))
+	if event.OwnerID == nil {
+		t.Fatal("event has no owner")
+	}
+	if event.CreatorName != "Owner" {
+		t.Errorf("creator_name = %q, want the user's name", event.CreatorName)
+	}
+}
+
+func TestCreateEventValidation(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	date := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		req   map[string]any
+		field string
+	}{
+		{
+			name:  "missing title",
+			req:   eventRequest("", date),
+			field: "title",
+		},
+		{
+			name:  "no candidate dates",
+			req:   eventRequest("Lunch"),
+			field: "candidate_dates",
+		},
+		{
+			name: "invalid candidate date",
+			req: map[string]any{
+				"title":           "Lunch",
+				"candidate_dates": []string{"2030-01-10"},
+			},
+			field: "candidate_dates[0]",
+		},
+		{
+			name: "past deadline",
+			req: map[string]any{
+				"title":           "Lunch",
+				"candidate_dates": []string{rfc3339(date)},
+				"settings": map[string]any{
+					"deadline_enable": true,
+					"deadline":        rfc3339(time.Now().Add(-time.Hour)),
+				},
+			},
+			field: "settings.deadline",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := ts.request(http.MethodPost, "/api/v1/events", tt.req, nil)
+			apiErr := expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed)
+			if !slices.ContainsFunc(apiErr.Details, func(fe handlers.FieldError) bool { return fe.Field == tt.field }) {
+				t.Errorf("details = %+v, want an error for %s", apiErr.Details, tt.field)
+			}
+		})
+	}
+
+	resp := ts.request(http.MethodPost, "/api/v1/events", nil, map[string]string{"Content-Type": "application/json"})
+	expectError(t, resp, http.StatusBadRequest, handlers.CodeInvalidRequest)
+}
+
+func TestGetEventHidesUserIDs(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	owner := ts.signUp("owner@example.com", "Owner")
+	alice := ts.signUp("alice@example.com", "Alice")
+	bob := ts.signUp("bob@example.com", "Bob")
+
+	eventID := ts.createEvent(eventRequest("Planning", time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)), bearer(owner))
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+	for i, token := range []string{alice, bob} {
+		resp := ts.request(http.MethodPost, participantPath(eventID), vote(eventID, uint(i+1), "", ids, nil), bearer(token))
+		decodeJSON(t, resp, http.StatusCreated, nil)
+	}
+
+	type participant struct {
+		Name   string  `json:"name"`
+		UserID *string `json:"user_id"`
+	}
+	var body struct {
+		OwnerID      *string       `json:"owner_id"`
+		Participants []participant `json:"participants"`
+	}
+	var export struct {
+		Event struct {
+			OwnerID *string `json:"owner_id"`
+		} `json:"event"`
+		Participants []participant `json:"participants"`
+	}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		owner   bool
+		// user_id が見える参加者
+		visible []string
+	}{
+		{"owner", bearer(owner), true, []string{"Alice", "Bob"}},
+		{"participant", bearer(alice), false, []string{"Alice"}},
+		{"anonymous", nil, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(path string, ownerID *string, participants []participant) {
+				t.Helper()
+				if (ownerID != nil) != tt.owner {
+					t.Errorf("%s: owner_id = %v, want shown: %v", path, ownerID, tt.owner)
+				}
+				var visible []string
+				for _, p := range participants {
+					if p.UserID != nil {
+						visible = append(visible, p.Name)
+					}
+				}
+				if !slices.Equal(visible, tt.visible) {
+					t.Errorf("%s: user_id shown for %v, want %v", path, visible, tt.visible)
+				}
+			}
+
+			for _, path := range []string{"/api/v1/events/" + eventID, "/api/v1/events/" + eventID + "?include=participants"} {
+				body.OwnerID, body.Participants = nil, nil
+				decodeJSON(t, ts.request(http.MethodGet, path, nil, tt.headers), http.StatusOK, &body)
+				check(path, body.OwnerID, body.Participants)
+			}
+			export.Event.OwnerID, export.Participants = nil, nil
+			path := "/api/v1/events/" + eventID + "/export"
+			decodeJSON(t, ts.request(http.MethodGet, path, nil, tt.headers), http.StatusOK, &export)
+			check(path, export.Event.OwnerID, export.Participants)
+		})
+	}
+}
+
+func TestGetEventNotFound(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	resp := ts.request(http.MethodGet, "/api/v1/events/00000000-0000-0000-0000-000000000000", nil, nil)
+	expectError(t, resp, http.StatusNotFound, handlers.CodeEventNotFound)
+}
+
+func TestRegisterParticipant(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	eventID := ts.createEvent(eventRequest("Team lunch", first, first.AddDate(0, 0, 1)), nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+
+	resp := ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "Alice", ids[:1], ids[1:]), nil)
+	var participant models.Participant
+	decodeJSON(t, resp, http.StatusCreated, &participant)
+	if participant.ID != 1 || participant.Name != "Alice" || len(participant.Responses) != 2 {
+		t.Fatalf("participant = %+v", participant)
+	}
+
+	event := ts.getEvent(eventID, nil)
+	if len(event.Participants) != 1 {
+		t.Fatalf("participants = %d, want 1", len(event.Participants))
+	}
+	statuses := map[uint]string{}
+	for _, response := range event.Participants[0].Responses {
+		statuses[response.CandidateDateID] = response.Status
+	}
+	if statuses[ids[0]] != "available" || statuses[ids[1]] != "unavailable" {
+		t.Errorf("responses = %v", statuses)
+	}
+
+	resp = ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "Alice again", ids, nil), nil)
+	expectError(t, resp, http.StatusConflict, handlers.CodeParticipantExists)
+}
+
+func TestRegisterParticipantWithLogin(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	token := ts.signUp("bob@example.com", "Bob")
+
+	eventID := ts.createEvent(eventRequest("Team lunch", time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)), nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+
+	// 名前を省略するとユーザー名を使う
+	resp := ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "", ids, nil), bearer(token))
+	var participant models.Participant
+	decodeJSON(t, resp, http.StatusCreated, &participant)
+	if participant.Name != "Bob" || participant.UserID == nil {
+		t.Fatalf("participant = %+v", participant)
+	}
+
+	// 同じユーザーは別の参加者 ID でも2回目を登録できない
+	resp = ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 2, "", ids, nil), bearer(token))
+	expectError(t, resp, http.StatusConflict, handlers.CodeParticipantExists)
+}
+
+func TestRegisterParticipantValidation(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	date := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	eventID := ts.createEvent(eventRequest("Team lunch", date), nil)
+	otherID := ts.createEvent(eventRequest("Dinner", date), nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+	otherIDs := candidateDateIDs(t, ts.getEvent(otherID, nil))
+
+	tests := []struct {
+		name  string
+		req   map[string]any
+		field string
+	}{
+		{
+			name:  "missing name",
+			req:   vote(eventID, 1, "", ids, nil),
+			field: "name",
+		},
+		{
+			name:  "event id mismatch",
+			req:   vote(otherID, 1, "Alice", ids, nil),
+			field: "event_id",
+		},
+		{
+			name:  "candidate date of another event",
+			req:   vote(eventID, 1, "Alice", otherIDs, nil),
+			field: "available_candidate_dates[0].id",
+		},
+		{
+			name:  "both available and unavailable",
+			req:   vote(eventID, 1, "Alice", ids, ids),
+			field: "unavailable_candidate_dates[0].id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := ts.request(http.MethodPost, participantPath(eventID), tt.req, nil)
+			apiErr := expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed)
+			if !slices.ContainsFunc(apiErr.Details, func(fe handlers.FieldError) bool { return fe.Field == tt.field }) {
+				t.Errorf("details = %+v, want an error for %s", apiErr.Details, tt.field)
+			}
+		})
+	}
+
+	resp := ts.request(http.MethodPost, participantPath("00000000-0000-0000-0000-000000000000"),
+		vote("00000000-0000-0000-0000-000000000000", 1, "Alice", ids, nil), nil)
+	expectError(t, resp, http.StatusNotFound, handlers.CodeEventNotFound)
+}
+
+func TestRegisterParticipantLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxParticipantsPerEvent = 2
+	ts := newTestServer(t, cfg)
+
+	eventID := ts.createEvent(eventRequest("Team lunch", time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)), nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+
+	for i, name := range []string{"Alice", "Bob"} {
+		resp := ts.request(http.MethodPost, participantPath(eventID), vote(eventID, uint(i+1), name, ids, nil), nil)
+		decodeJSON(t, resp, http.StatusCreated, nil)
+	}
+	resp := ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 3, "Carol", ids, nil), nil)
+	expectError(t, resp, http.StatusConflict, handlers.CodeEventFull)
+}
+
+func settingsPath(eventID string) string {
+	return "/api/v1/events/" + eventID + "/settings"
+}
+
+func TestUpdateEventSettings(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	eventID := ts.createEvent(eventRequest("Team lunch", time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)), nil)
+	deadline := time.Now().Add(24 * time.Hour).Truncate(time.Second).UTC()
+
+	resp := ts.request(http.MethodPut, settingsPath(eventID), map[string]any{
+		"allow_setting_changes":   true,
+		"deadline_enable":         true,
+		"deadline":                rfc3339(deadline),
+		"auto_decision_enable":    true,
+		"auto_decision_threshold": 3,
+		"rss_enabled":             true,
+	}, nil)
+	decodeJSON(t, resp, http.StatusOK, nil)
+
+	event := ts.getEvent(eventID, nil)
+	if !event.DeadlineEnable || event.Deadline == nil || !event.Deadline.Equal(deadline) {
+		t.Errorf("deadline = %v (enabled %v), want %s", event.Deadline, event.DeadlineEnable, deadline)
+	}
+	if !event.AutoDecisionEnable || event.AutoDecisionThreshold != 3 || !event.RSSEnabled {
+		t.Errorf("settings = %+v", event)
+	}
+
+	resp = ts.request(http.MethodPut, settingsPath(eventID), map[string]any{
+		"deadline_enable": true,
+		"deadline":        rfc3339(time.Now().Add(-time.Hour)),
+	}, nil)
+	apiErr := expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed)
+	if len(apiErr.Details) != 1 || apiErr.Details[0].Field != "deadline" {
+		t.Errorf("details = %+v", apiErr.Details)
+	}
+
+	// 既存の締切はそのまま送り直せる
+	resp = ts.request(http.MethodPut, settingsPath(eventID), map[string]any{
+		"allow_setting_changes": true,
+		"deadline_enable":       true,
+		"deadline":              rfc3339(deadline),
+	}, nil)
+	decodeJSON(t, resp, http.StatusOK, nil)
+
+	resp = ts.request(http.MethodPut, settingsPath("00000000-0000-0000-0000-000000000000"), map[string]any{}, nil)
+	expectError(t, resp, http.StatusNotFound, handlers.CodeEventNotFound)
+}
+
+func TestUpdateEventSettingsLocked(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	owner := ts.signUp("owner@example.com", "Owner")
+	other := ts.signUp("other@example.com", "Other")
+
+	req := eventRequest("Team lunch", time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC))
+	req["settings"] = map[string]any{"allow_setting_changes": false}
+	eventID := ts.createEvent(req, bearer(owner))
+
+	unlock := map[string]any{"allow_setting_changes": true, "rss_enabled": true}
+	for name, headers := range map[string]map[string]string{"anonymous": nil, "other user": bearer(other)} {
+		t.Run(name, func(t *testing.T) {
+			resp := ts.request(http.MethodPut, settingsPath(eventID), unlock, headers)
+			expectError(t, resp, http.StatusForbidden, handlers.CodeSettingsLocked)
+		})
+	}
+
+	// 主催者は変更不可にしたイベントでも変更できる
+	resp := ts.request(http.MethodPut, settingsPath(eventID), unlock, bearer(owner))
+	decodeJSON(t, resp, http.StatusOK, nil)
+	if event := ts.getEvent(eventID, nil); !event.AllowSettingChanges || !event.RSSEnabled {
+		t.Errorf("settings were not updated: %+v", event)
+	}
+}
+
+func TestEventRSS(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	eventID := ts.createEvent(eventRequest("Team lunch", time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)), nil)
+
+	resp := ts.request(http.MethodGet, "/api/v1/rss/"+eventID+"/feed", nil, nil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/rss+xml") {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{"<rss", "<title>Team lunch</title>", "https://yotei.example.com/" + eventID + "/vote"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("feed does not contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(string(body), "<item>") {
+		t.Errorf("feed of an undecided event has items:\n%s", body)
+	}
+
+	resp = ts.request(http.MethodGet, "/api/v1/rss/00000000-0000-0000-0000-000000000000/feed", nil, nil)
+	expectError(t, resp, http.StatusNotFound, handlers.CodeEventNotFound)
+}
+
+func TestCandidateDateLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxCandidateDatesPerEvent = 2
+	ts := newTestServer(t, cfg)
+	token := ts.signUp("organizer@example.com", "Organizer")
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	dates := []time.Time{first, first.AddDate(0, 0, 1), first.AddDate(0, 0, 2)}
+
+	ts.createEvent(eventRequest("Two dates", dates[:2]...), nil)
+	resp := ts.request(http.MethodPost, "/api/v1/events", eventRequest("Three dates", dates...), nil)
+	if apiErr := expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed); apiErr.Details[0].Field != "candidate_dates" {
+		t.Errorf("details = %+v, want candidate_dates", apiErr.Details)
+	}
+
+	// 上限を下げる前に作成したイベント
+	event := &models.Event{ID: "00000000-0000-4000-8000-000000000001", Title: "Created before", CreatorName: "Organizer"}
+	for _, date := range dates {
+		event.CandidateDates = append(event.CandidateDates, models.CandidateDate{EventID: event.ID, DateTime: date})
+	}
+	if err := ts.store.CreateEvent(context.Background(), event); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	resp = ts.request(http.MethodPost, "/api/v1/events/"+event.ID+"/clone", nil, nil)
+	expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed)
+
+	var export map[string]any
+	decodeJSON(t, ts.request(http.MethodGet, "/api/v1/events/"+event.ID+"/export", nil, nil), http.StatusOK, &export)
+	resp = ts.request(http.MethodPost, "/api/v1/events/import", export, bearer(token))
+	if apiErr := expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed); apiErr.Details[0].Field != "candidate_dates" {
+		t.Errorf("details = %+v, want candidate_dates", apiErr.Details)
+	}
+
+	// 翌週の月曜と火曜に2回ずつで4件になる
+	series := map[string]any{
+		"title":    "Weekly",
+		"schedule": "0 9 * * 1",
+		"candidate_rule": map[string]any{
+			"period":   "next_week",
+			"weekdays": []int{1, 2},
+			"times":    []string{"10:00", "15:00"},
+		},
+	}
+	resp = ts.request(http.MethodPost, "/api/v1/series", series, bearer(token))
+	if apiErr := expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed); apiErr.Details[0].Field != "candidate_rule" {
+		t.Errorf("details = %+v, want candidate_rule", apiErr.Details)
+	}
+	series["candidate_rule"].(map[string]any)["times"] = []string{"10:00"}
+	decodeJSON(t, ts.request(http.MethodPost, "/api/v1/series", series, bearer(token)), http.StatusCreated, nil)
+
+	// 第5週は最大3日
+	series["candidate_rule"] = map[string]any{
+		"period":   "next_month",
+		"weeks":    []int{5},
+		"weekdays": []int{0, 1, 2, 3, 4, 5, 6},
+		"times":    []string{"10:00"},
+	}
+	expectError(t, ts.request(http.MethodPost, "/api/v1/series", series, bearer(token)), http.StatusBadRequest, handlers.CodeValidationFailed)
+	series["candidate_rule"].(map[string]any)["weekdays"] = []int{1, 3}
+	decodeJSON(t, ts.request(http.MethodPost, "/api/v1/series", series, bearer(token)), http.StatusCreated, nil)
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"yotei-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetEvent の include で指定できる関連データ
+const (
+	// 候補日（回答は含まない）
+	includeCandidateDates = "candidate_dates"
+	// 候補日ごとの回答数。candidate_dates を含む
+	includeCounts = "counts"
+	// 参加者（回答は含まない）
+	includeParticipants = "participants"
+	// 参加者ごとの回答を候補日IDをキーにしたマップで返す。participants を含む
+	includeResponses = "responses"
+)
+
+var eventIncludes = []string{includeCandidateDates, includeCounts, includeParticipants, includeResponses}
+
+// fields で指定できるイベントの項目。関連データは include で指定する
+var eventFields = func() []string {
+	var fields []string
+	t := reflect.TypeFor[models.Event]()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && name != "candidate_dates" && name != "participants" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}()
+
+type CandidateDateCounts struct {
+	Available   int64 `json:"available"`
+	Maybe       int64 `json:"maybe"`
+	Unavailable int64 `json:"unavailable"`
+	NoAnswer    int64 `json:"no_answer"`
+}
+
+type CandidateDateView struct {
+	ID       uint                 `json:"id"`
+	DateTime time.Time            `json:"date_time"`
+	Counts   *CandidateDateCounts `json:"counts,omitempty"`
+}
+
+type ParticipantView struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	UserID    *string   `json:"user_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// 候補日ID -> 回答（available / maybe / unavailable）。回答していない候補日は含まない
+	Responses map[uint]string `json:"responses,omitempty"`
+}
+
+// parseListParam はカンマ区切りのクエリパラメータを読み、allowed にない値があれば検証エラーにする
+func parseListParam(verr *ValidationError, name, value string, allowed []string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !slices.Contains(allowed, v) {
+			verr.Add(name, fmt.Sprintf("contains unknown value %q; must be one of: %s", v, strings.Join(allowed, ", ")))
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// getEventView は include と fields で指定された項目と関連データだけを返す。
+// 候補日と参加者の両方に回答を入れ子にする GetEvent の既定の形より小さく、回答は表形式で返す
+func (h *Handler) getEventView(c *fiber.Ctx) error {
+	verr := &ValidationError{}
+	includes := parseListParam(verr, "include", c.Query("include"), eventIncludes)
+	fields := parseListParam(verr, "fields", c.Query("fields"), eventFields)
+	if err := verr.OrNil(); err != nil {
+		return err
+	}
+
+	ctx := c.UserContext()
+	event, err := h.store.GetEvent(ctx, c.Params("id"))
+	if err != nil {
+		return eventLookupError(err)
+	}
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
+	}
+
+	showUserIDs := showsUserIDs(c, event)
+	if !showUserIDs {
+		event.OwnerID = nil
+	}
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return internalError("Failed to encode event", err)
+	}
+	var base map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &base); err != nil {
+		return internalError("Failed to encode event", err)
+	}
+	response := map[string]any{}
+	for name, value := range base {
+		if len(fields) == 0 || name == "id" || slices.Contains(fields, name) {
+			response[name] = value
+		}
+	}
+	delete(response, "candidate_dates")
+	delete(response, "participants")
+
+	withCounts := slices.Contains(includes, includeCounts)
+	withResponses := slices.Contains(includes, includeResponses)
+
+	if withResponses || slices.Contains(includes, includeParticipants) {
+		participants, err := h.store.ListParticipants(ctx, event.ID, withResponses)
+		if err != nil {
+			return internalError("Failed to get participants", err)
+		}
+		views := make([]ParticipantView, len(participants))
+		for i, participant := range participants {
+			views[i] = ParticipantView{
+				ID:        participant.ID,
+				Name:      participant.Name,
+				UserID:    participant.UserID,
+				CreatedAt: participant.CreatedAt,
+				UpdatedAt: participant.UpdatedAt,
+			}
+			if !showUserIDs {
+				views[i].UserID = ownUserID(c, participant.UserID)
+			}
+			if withResponses {
+				views[i].Responses = make(map[uint]string, len(participant.Responses))
+				for _, r := range participant.Responses {
+					views[i].Responses[r.CandidateDateID] = r.Status
+				}
+			}
+		}
+		response["participants"] = views
+	}
+
+	if withCounts || slices.Contains(includes, includeCandidateDates) {
+		tallies, err := h.store.TallyResponses(ctx, event.ID)
+		if err != nil {
+			return internalError("Failed to count responses", err)
+		}
+		var participantCount int64
+		if withCounts {
+			if participantCount, err = h.store.CountParticipants(ctx, event.ID); err != nil {
+				return internalError("Failed to count participants", err)
+			}
+		}
+		views := make([]CandidateDateView, len(tallies))
+		for i, tally := range tallies {
+			views[i] = CandidateDateView{ID: tally.CandidateDateID, DateTime: tally.DateTime}
+			if withCounts {
+				summary := candidateDateSummary(tally, participantCount, false)
+				views[i].Counts = &CandidateDateCounts{
+					Available:   summary.Available,
+					Maybe:       summary.Maybe,
+					Unavailable: summary.Unavailable,
+					NoAnswer:    summary.NoAnswer,
+				}
+			}
+		}
+		response["candidate_dates"] = views
+	}
+
+	return c.JSON(response)
+}
@@ -0,0 +1,72 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+)
+
+// eventView は include と fields を付けたイベントのレスポンス
+type eventView struct {
+	ID             string                       `json:"id"`
+	Title          *string                      `json:"title"`
+	Description    *string                      `json:"description"`
+	CandidateDates []handlers.CandidateDateView `json:"candidate_dates"`
+	Participants   []handlers.ParticipantView   `json:"participants"`
+}
+
+func TestGetEventView(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	eventID := ts.createEvent(eventRequest("Team lunch", first, first.AddDate(0, 0, 1)), nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "Alice", ids[:1], ids[1:]), nil), http.StatusCreated, nil)
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 2, "Bob", ids[:1], nil), nil), http.StatusCreated, nil)
+	path := "/api/v1/events/" + eventID
+
+	// fields だけの場合は関連データを含めない
+	var view eventView
+	decodeJSON(t, ts.request(http.MethodGet, path+"?fields=title", nil, nil), http.StatusOK, &view)
+	if view.ID != eventID || view.Title == nil || *view.Title != "Team lunch" || view.Description != nil {
+		t.Fatalf("fields=title: %+v", view)
+	}
+	if view.CandidateDates != nil || view.Participants != nil {
+		t.Fatalf("fields=title includes relations: %+v", view)
+	}
+
+	view = eventView{}
+	decodeJSON(t, ts.request(http.MethodGet, path+"?include=counts,responses", nil, nil), http.StatusOK, &view)
+	if len(view.CandidateDates) != 2 || len(view.Participants) != 2 {
+		t.Fatalf("include=counts,responses: %+v", view)
+	}
+	wantCounts := []handlers.CandidateDateCounts{
+		{Available: 2},
+		{Unavailable: 1, NoAnswer: 1},
+	}
+	for i, candidateDate := range view.CandidateDates {
+		if candidateDate.ID != ids[i] || candidateDate.Counts == nil || *candidateDate.Counts != wantCounts[i] {
+			t.Errorf("candidate_dates[%d] = %+v, want counts %+v", i, candidateDate, wantCounts[i])
+		}
+	}
+	alice := view.Participants[0]
+	if alice.Name != "Alice" || alice.Responses[ids[0]] != "available" || alice.Responses[ids[1]] != "unavailable" {
+		t.Errorf("participants[0] = %+v", alice)
+	}
+	if bob := view.Participants[1]; len(bob.Responses) != 1 {
+		t.Errorf("participants[1] responses = %v, want only the answered date", bob.Responses)
+	}
+
+	// 回答数を頼まなければ候補日に counts を付けず、参加者に回答を付けない
+	view = eventView{}
+	decodeJSON(t, ts.request(http.MethodGet, path+"?include=candidate_dates,participants", nil, nil), http.StatusOK, &view)
+	if len(view.CandidateDates) != 2 || view.CandidateDates[0].Counts != nil || len(view.Participants) != 2 || view.Participants[0].Responses != nil {
+		t.Fatalf("include=candidate_dates,participants: %+v", view)
+	}
+
+	expectError(t, ts.request(http.MethodGet, path+"?include=votes", nil, nil), http.StatusBadRequest, handlers.CodeValidationFailed)
+	expectError(t, ts.request(http.MethodGet, path+"?fields=title,password", nil, nil), http.StatusBadRequest, handlers.CodeValidationFailed)
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events/00000000-0000-0000-0000-000000000000?include=counts", nil, nil), http.StatusNotFound, handlers.CodeEventNotFound)
+}
@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"yotei-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// EventExportVersion は書き出し形式のバージョン。互換性のない変更をした場合に上げる
+const EventExportVersion = 1
+
+// EventExport はバックアップや環境間の移行に使うイベントの書き出し形式
+type EventExport struct {
+	Version        int                     `json:"version"`
+	ExportedAt     time.Time               `json:"exported_at"`
+	Event          ExportedEvent           `json:"event"`
+	Settings       ExportedSettings        `json:"settings"`
+	Decision       ExportedDecision        `json:"decision"`
+	CandidateDates []ExportedCandidateDate `json:"candidate_dates" validate:"required,min=1,dive"`
+	Participants   []ExportedParticipant   `json:"participants" validate:"dive"`
+	Responses      []ExportedResponse      `json:"responses" validate:"dive"`
+	FeedItems      []ExportedFeedItem      `json:"feed_items" validate:"dive"`
+}
+
+type ExportedEvent struct {
+	ID          string `json:"id" validate:"required,uuid"`
+	Title       string `json:"title" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=10000"`
+	CreatorName string `json:"creator_name" validate:"max=100"`
+	// 書き出し元の環境のユーザーとワークスペース。インポート時には使わない
+	OwnerID     *string   `json:"owner_id,omitempty"`
+	WorkspaceID *string   `json:"workspace_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ExportedSettings struct {
+	AllowSettingChanges   bool       `json:"allow_setting_changes"`
+	DeadlineEnable        bool       `json:"deadline_enable"`
+	Deadline              *time.Time `json:"deadline"`
+	AutoDecisionEnable    bool       `json:"auto_decision_enable"`
+	AutoDecisionThreshold int        `json:"auto_decision_threshold" validate:"min=0"`
+	RSSEnabled            bool       `json:"rss_enabled"`
+}
+
+// ExportedDecision は締切・自動決定の処理が済んでいるか。決定した候補日は feed_items の通知に含まれる
+type ExportedDecision struct {
+	DeadlineReached     bool `json:"deadline_reached"`
+	AutoDecisionReached bool `json:"auto_decision_reached"`
+}
+
+type ExportedCandidateDate struct {
+	ID        uint      `json:"id" validate:"required"`
+	DateTime  time.Time `json:"date_time" validate:"required"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExportedParticipant struct {
+	ID   uint   `json:"id" validate:"required"`
+	Name string `json:"name" validate:"max=100"`
+	// 書き出し元の環境のユーザー。インポート時には使わない
+	UserID    *string   `json:"user_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExportedResponse struct {
+	ID              uint      `json:"id" validate:"required"`
+	ParticipantID   uint      `json:"participant_id" validate:"required"`
+	CandidateDateID uint      `json:"candidate_date_id" validate:"required"`
+	Status          string    `json:"status" validate:"required,oneof=available maybe unavailable"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type ExportedFeedItem struct {
+	ID          uint      `json:"id" validate:"required"`
+	Title       string    `json:"title" validate:"required,max=255"`
+	Link        string    `json:"link"`
+	Description string    `json:"description" validate:"required"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ImportOptions struct {
+	// 書き出し元の ID をそのまま使う。false の場合はイベントと子のレコードに新しい ID を振る
+	PreserveIDs bool
+	// インポートしたイベントの作成者とワークスペース。書き出し元の値は環境ごとに異なるので引き継がない
+	OwnerID     *string
+	WorkspaceID *string
+}
+
+type ImportEventResponse struct {
+	ID string `json:"id"`
+}
+
+// ExportEvent はイベントを関連データごと JSON で書き出す
+func (h *Handler) ExportEvent(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	event, err := h.store.GetEvent(ctx, c.Params("id"))
+	if err != nil {
+		return eventLookupError(err)
+	}
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
+	}
+
+	export, err := h.BuildEventExport(ctx, event.ID)
+	if err != nil {
+		return internalError("Failed to export event", err)
+	}
+	// GetEvent と同じく、誰でも参照できるイベントのユーザーIDは作成者にだけ見せる
+	if !showsUserIDs(c, event) {
+		export.Event.OwnerID = nil
+		for i := range export.Participants {
+			export.Participants[i].UserID = ownUserID(c, export.Participants[i].UserID)
+		}
+	}
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(event.ID+".json", event.Title+".json"))
+	return c.JSON(export)
+}
+
+// ImportEvent は ExportEvent で書き出したイベントを、ログイン中のユーザーのイベントとして作成する
+func (h *Handler) ImportEvent(c *fiber.Ctx) error {
+	var export EventExport
+	if err := c.BodyParser(&export); err != nil {
+		return ErrInvalidRequest
+	}
+
+	// ID を指定した作成はシーケンスを任意の値まで進められるので、管理者が使う CLI に限る
+	if c.QueryBool("preserve_ids") {
+		verr := &ValidationError{}
+		verr.Add("preserve_ids", "is only available from the CLI (yotei-backend event import -preserve-ids)")
+		return verr
+	}
+
+	user := currentUser(c)
+	opts := ImportOptions{OwnerID: &user.ID}
+	if workspaceID := c.Query("workspace_id"); workspaceID != "" {
+		if _, err := h.workspaceMember(c, workspaceID, models.WorkspaceRoleOwner, models.WorkspaceRoleOrganizer); err != nil {
+			return err
+		}
+		opts.WorkspaceID = &workspaceID
+	}
+
+	event, err := h.RestoreEventExport(c.UserContext(), &export, opts)
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr
+	}
+	if err != nil {
+		return internalError("Failed to import event", err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(ImportEventResponse{ID: event.ID})
+}
+
+// BuildEventExport はイベントの書き出し内容を作成する。CLI の event export からも使う
+func (h *Handler) BuildEventExport(ctx context.Context, eventID string) (*EventExport, error) {
+	event, err := h.store.GetEventDetails(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	feeds, err := h.store.ListFeeds(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &EventExport{
+		Version:    EventExportVersion,
+		ExportedAt: time.Now(),
+		Event: ExportedEvent{
+			ID:          event.ID,
+			Title:       event.Title,
+			Description: event.Description,
+			CreatorName: event.CreatorName,
+			OwnerID:     event.OwnerID,
+			WorkspaceID: event.WorkspaceID,
+			CreatedAt:   event.CreatedAt,
+			UpdatedAt:   event.UpdatedAt,
+		},
+		Settings: ExportedSettings{
+			AllowSettingChanges:   event.AllowSettingChanges,
+			DeadlineEnable:        event.DeadlineEnable,
+			Deadline:              event.Deadline,
+			AutoDecisionEnable:    event.AutoDecisionEnable,
+			AutoDecisionThreshold: event.AutoDecisionThreshold,
+			RSSEnabled:            event.RSSEnabled,
+		},
+		Decision: ExportedDecision{
+			DeadlineReached:     event.DeadlineReached,
+			AutoDecisionReached: event.AutoDecisionReached,
+		},
+		CandidateDates: []ExportedCandidateDate{},
+		Participants:   []ExportedParticipant{},
+		Responses:      []ExportedResponse{},
+		FeedItems:      []ExportedFeedItem{},
+	}
+	for _, candidateDate := range event.CandidateDates {
+		export.CandidateDates = append(export.CandidateDates, ExportedCandidateDate{
+			ID:        candidateDate.ID,
+			DateTime:  candidateDate.DateTime,
+			CreatedAt: candidateDate.CreatedAt,
+			UpdatedAt: candidateDate.UpdatedAt,
+		})
+	}
+	for _, participant := range event.Participants {
+		export.Participants = append(export.Participants, ExportedParticipant{
+			ID:        participant.ID,
+			Name:      participant.Name,
+			UserID:    participant.UserID,
+			CreatedAt: participant.CreatedAt,
+			UpdatedAt: participant.UpdatedAt,
+		})
+		for _, response := range participant.Responses {
+			export.Responses = append(export.Responses, ExportedResponse{
+				ID:              response.ID,
+				ParticipantID:   response.ParticipantID,
+				CandidateDateID: response.CandidateDateID,
+				Status:          response.Status,
+				CreatedAt:       response.CreatedAt,
+				UpdatedAt:       response.UpdatedAt,
+			})
+		}
+	}
+	for _, feed := range feeds {
+		export.FeedItems = append(export.FeedItems, ExportedFeedItem{
+			ID:          feed.ID,
+			Title:       feed.Title,
+			Link:        feed.Link,
+			Description: feed.Description,
+			CreatedAt:   feed.CreatedAt,
+		})
+	}
+	return export, nil
+}
+
+// RestoreEventExport は書き出したイベントを作成する。内容に誤りがある場合は *ValidationError、
+// ID を保持してすでに同じ ID のレコードがある場合は store.ErrConflict を返す
+func (h *Handler) RestoreEventExport(ctx context.Context, export *EventExport, opts ImportOptions) (*models.Event, error) {
+	if err := h.validateEventExport(export); err != nil {
+		return nil, err
+	}
+
+	event := &models.Event{
+		ID:                    export.Event.ID,
+		Title:                 export.Event.Title,
+		Description:           export.Event.Description,
+		CreatorName:           export.Event.CreatorName,
+		OwnerID:               opts.OwnerID,
+		WorkspaceID:           opts.WorkspaceID,
+		CreatedAt:             export.Event.CreatedAt,
+		UpdatedAt:             export.Event.UpdatedAt,
+		DeadlineReached:       export.Decision.DeadlineReached,
+		AutoDecisionReached:   export.Decision.AutoDecisionReached,
+		AllowSettingChanges:   export.Settings.AllowSettingChanges,
+		DeadlineEnable:        export.Settings.DeadlineEnable,
+		Deadline:              export.Settings.Deadline,
+		AutoDecisionEnable:    export.Settings.AutoDecisionEnable,
+		AutoDecisionThreshold: export.Settings.AutoDecisionThreshold,
+		RSSEnabled:            export.Settings.RSSEnabled,
+	}
+	if !opts.PreserveIDs {
+		event.ID = uuid.New().String()
+	}
+
+	for _, candidateDate := range export.CandidateDates {
+		event.CandidateDates = append(event.CandidateDates, models.CandidateDate{
+			ID:        candidateDate.ID,
+			DateTime:  candidateDate.DateTime,
+			CreatedAt: candidateDate.CreatedAt,
+			UpdatedAt: candidateDate.UpdatedAt,
+		})
+	}
+	participantIndex := make(map[uint]int, len(export.Participants))
+	for i, participant := range export.Participants {
+		participantIndex[participant.ID] = i
+		event.Participants = append(event.Participants, models.Participant{
+			ID:        participant.ID,
+			Name:      participant.Name,
+			CreatedAt: participant.CreatedAt,
+			UpdatedAt: participant.UpdatedAt,
+		})
+	}
+	for _, response := range export.Responses {
+		participant := &event.Participants[participantIndex[response.ParticipantID]]
+		participant.Responses = append(participant.Responses, models.Response{
+			ID:              response.ID,
+			CandidateDateID: response.CandidateDateID,
+			Status:          response.Status,
+			CreatedAt:       response.CreatedAt,
+			UpdatedAt:       response.UpdatedAt,
+		})
+	}
+	// 通知のリンクはフロントエンドの URL とイベント ID から作り直す
+	feeds := make([]models.RSSFeed, 0, len(export.FeedItems))
+	for _, item := range export.FeedItems {
+		feeds = append(feeds, models.RSSFeed{
+			ID:          item.ID,
+			Title:       item.Title,
+			Link:        h.voteURL(event.ID),
+			Description: item.Description,
+			CreatedAt:   item.CreatedAt,
+		})
+	}
+
+	if err := h.store.ImportEvent(ctx, event, feeds, opts.PreserveIDs); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// validateEventExport は項目の形式に加えて、回答が書き出し内の参加者と候補日を参照しているかを確認する
+func (h *Handler) validateEventExport(export *EventExport) error {
+	if export.Version != EventExportVersion {
+		verr := &ValidationError{}
+		verr.Add("version", fmt.Sprintf("must be %d", EventExportVersion))
+		return verr
+	}
+	if err := validateStruct(export); err != nil {
+		return err
+	}
+
+	verr := &ValidationError{}
+	if limit := h.config.MaxCandidateDatesPerEvent; limit > 0 && len(export.CandidateDates) > limit {
+		verr.Add("candidate_dates", fmt.Sprintf("must contain at most %d item(s)", limit))
+	}
+	if limit := h.config.MaxParticipantsPerEvent; limit > 0 && len(export.Participants) > limit {
+		verr.Add("participants", fmt.Sprintf("must contain at most %d item(s)", limit))
+	}
+	candidateDateIDs := map[uint]bool{}
+	for i, candidateDate := range export.CandidateDates {
+		if candidateDateIDs[candidateDate.ID] {
+			verr.Add(fmt.Sprintf("candidate_dates[%d].id", i), "must not contain duplicates")
+		}
+		candidateDateIDs[candidateDate.ID] = true
+	}
+	participantIDs := map[uint]bool{}
+	for i, participant := range export.Participants {
+		if participantIDs[participant.ID] {
+			verr.Add(fmt.Sprintf("participants[%d].id", i), "must not contain duplicates")
+		}
+		participantIDs[participant.ID] = true
+	}
+	type answer struct{ participantID, candidateDateID uint }
+	answers := map[answer]bool{}
+	responseIDs := map[uint]bool{}
+	for i, response := range export.Responses {
+		field := fmt.Sprintf("responses[%d]", i)
+		if responseIDs[response.ID] {
+			verr.Add(field+".id", "must not contain duplicates")
+		}
+		responseIDs[response.ID] = true
+		if !participantIDs[response.ParticipantID] {
+			verr.Add(field+".participant_id", "must refer to a participant in this export")
+		}
+		if !candidateDateIDs[response.CandidateDateID] {
+			verr.Add(field+".candidate_date_id", "must refer to a candidate date in this export")
+		}
+		key := answer{response.ParticipantID, response.CandidateDateID}
+		if answers[key] {
+			verr.Add(field, "must not answer the same candidate date twice")
+		}
+		answers[key] = true
+	}
+	feedIDs := map[uint]bool{}
+	for i, item := range export.FeedItems {
+		if feedIDs[item.ID] {
+			verr.Add(fmt.Sprintf("feed_items[%d].id", i), "must not contain duplicates")
+		}
+		feedIDs[item.ID] = true
+	}
+	return verr.OrNil()
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Excel が UTF-8 と判定できるように先頭に付ける
+const utf8BOM = "\xEF\xBB\xBF"
+
+// CSV のセルの表記。symbols は日本語の見出し、words は英語の見出しになる
+const (
+	csvLabelsSymbols = "symbols"
+	csvLabelsWords   = "words"
+)
+
+type csvLabels struct {
+	name     string
+	statuses map[string]string
+	totals   map[string]string
+	weekdays [7]string
+	layout   string
+}
+
+var csvLabelSets = map[string]csvLabels{
+	csvLabelsSymbols: {
+		name:     "名前",
+		statuses: map[string]string{"available": "◯", "maybe": "△", "unavailable": "×"},
+		totals:   map[string]string{"available": "◯ 合計", "maybe": "△ 合計", "unavailable": "× 合計"},
+		weekdays: [7]string{"日", "月", "火", "水", "木", "金", "土"},
+		layout:   "2006/01/02(%s) 15:04",
+	},
+	csvLabelsWords: {
+		name:     "Name",
+		statuses: map[string]string{"available": "available", "maybe": "maybe", "unavailable": "unavailable"},
+		totals:   map[string]string{"available": "Total available", "maybe": "Total maybe", "unavailable": "Total unavailable"},
+		weekdays: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		layout:   "2006-01-02 (%s) 15:04",
+	},
+}
+
+var csvStatuses = []string{"available", "maybe", "unavailable"}
+
+func (l csvLabels) formatDate(t time.Time) string {
+	return t.Format(fmt.Sprintf(l.layout, l.weekdays[t.Weekday()]))
+}
+
+// ExportEventCSV は参加者 × 候補日の回答表と回答数の合計行を CSV で返す
+func (h *Handler) ExportEventCSV(c *fiber.Ctx) error {
+	verr := &ValidationError{}
+	labels, ok := csvLabelSets[c.Query("labels", csvLabelsSymbols)]
+	if !ok {
+		verr.Add("labels", fmt.Sprintf("must be one of: %s, %s", csvLabelsSymbols, csvLabelsWords))
+	}
+	location := h.location()
+	if tz := c.Query("tz"); tz != "" {
+		loaded, err := time.LoadLocation(tz)
+		if err != nil {
+			verr.Add("tz", "must be an IANA time zone name")
+		} else {
+			location = loaded
+		}
+	}
+	if err := verr.OrNil(); err != nil {
+		return err
+	}
+
+	ctx := c.UserContext()
+	event, err := h.store.GetEvent(ctx, c.Params("id"))
+	if err != nil {
+		return eventLookupError(err)
+	}
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
+	}
+
+	tallies, err := h.store.TallyResponses(ctx, event.ID)
+	if err != nil {
+		return internalError("Failed to count responses", err)
+	}
+	participants, err := h.store.ListParticipants(ctx, event.ID, true)
+	if err != nil {
+		return internalError("Failed to get participants", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(utf8BOM)
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true
+
+	header := []string{labels.name}
+	for _, tally := range tallies {
+		header = append(header, labels.formatDate(tally.DateTime.In(location)))
+	}
+	w.Write(header)
+
+	for _, participant := range participants {
+		statuses := make(map[uint]string, len(participant.Responses))
+		for _, response := range participant.Responses {
+			statuses[response.CandidateDateID] = response.Status
+		}
+		row := []string{csvSafe(participant.Name)}
+		for _, tally := range tallies {
+			row = append(row, labels.statuses[statuses[tally.CandidateDateID]])
+		}
+		w.Write(row)
+	}
+
+	for _, status := range csvStatuses {
+		row := []string{labels.totals[status]}
+		for _, tally := range tallies {
+			var count int64
+			switch status {
+			case "available":
+				count = tally.Available
+			case "maybe":
+				count = tally.Maybe
+			case "unavailable":
+				count = tally.Unavailable
+			}
+			row = append(row, strconv.FormatInt(count, 10))
+		}
+		w.Write(row)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return internalError("Failed to write CSV", err)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(event.ID+".csv", event.Title+".csv"))
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+// csvSafe は表計算ソフトで数式として解釈される文字で始まる値の先頭に ' を付ける
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// contentDisposition は ASCII のファイル名に加えて、RFC 6266 の filename* で UTF-8 のファイル名を返す
+func contentDisposition(fallback, filename string) string {
+	var encoded strings.Builder
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback, encoded.String())
+}
+
+// RFC 8187 の attr-char
+func isAttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
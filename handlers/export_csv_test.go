@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+)
+
+// readCSV は BOM を確認して CSV のレコードを返す
+func readCSV(t *testing.T, resp *http.Response) [][]string {
+	t.Helper()
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !bytes.HasPrefix(body, []byte("\xEF\xBB\xBF")) {
+		t.Fatalf("body does not start with a UTF-8 BOM: %q", body)
+	}
+	if !bytes.Contains(body, []byte("\r\n")) {
+		t.Errorf("body does not use CRLF line endings: %q", body)
+	}
+	records, err := csv.NewReader(bytes.NewReader(body[3:])).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV %q: %v", body, err)
+	}
+	return records
+}
+
+func TestExportEventCSV(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	eventID := ts.createEvent(eventRequest("チーム ランチ", first, first.AddDate(0, 0, 1)), nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "=SUM(A1)", ids[:1], ids[1:]), nil), http.StatusCreated, nil)
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 2, `Bob, "the" builder`, ids, nil), nil), http.StatusCreated, nil)
+	path := "/api/v1/events/" + eventID + "/export.csv"
+
+	resp := ts.request(http.MethodGet, path, nil, nil)
+	if got := resp.Header.Get("Content-Disposition"); !strings.Contains(got, `filename="`+eventID+`.csv"`) || !strings.Contains(got, "filename*=UTF-8''%E3%83%81") {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	// 数式として解釈される名前は ' を付け、カンマや引用符は CSV のクォートで囲む
+	want := [][]string{
+		{"名前", "2030/01/10(木) 10:00", "2030/01/11(金) 10:00"},
+		{"'=SUM(A1)", "◯", "×"},
+		{`Bob, "the" builder`, "◯", "◯"},
+		{"◯ 合計", "2", "1"},
+		{"△ 合計", "0", "0"},
+		{"× 合計", "0", "1"},
+	}
+	if got := readCSV(t, resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+
+	records := readCSV(t, ts.request(http.MethodGet, path+"?labels=words&tz=Asia/Tokyo", nil, nil))
+	if got, want := records[0], []string{"Name", "2030-01-10 (Thu) 19:00", "2030-01-11 (Fri) 19:00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("header = %q, want %q", got, want)
+	}
+	if got := records[1][1:]; !reflect.DeepEqual(got, []string{"available", "unavailable"}) {
+		t.Errorf("first row = %q", got)
+	}
+
+	expectError(t, ts.request(http.MethodGet, path+"?labels=emoji", nil, nil), http.StatusBadRequest, handlers.CodeValidationFailed)
+	expectError(t, ts.request(http.MethodGet, path+"?tz=Mars/Olympus", nil, nil), http.StatusBadRequest, handlers.CodeValidationFailed)
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events/00000000-0000-0000-0000-000000000000/export.csv", nil, nil), http.StatusNotFound, handlers.CodeEventNotFound)
+}
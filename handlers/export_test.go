@@ -0,0 +1,84 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+)
+
+func TestExportAndImportEvent(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	token := ts.signUp("owner@example.com", "Owner")
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	eventID := ts.createEvent(eventRequest("Team lunch", first, first.AddDate(0, 0, 1)), bearer(token))
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "Alice", ids[:1], ids[1:]), nil), http.StatusCreated, nil)
+
+	var export handlers.EventExport
+	decodeJSON(t, ts.request(http.MethodGet, "/api/v1/events/"+eventID+"/export", nil, nil), http.StatusOK, &export)
+	if export.Version != handlers.EventExportVersion || len(export.CandidateDates) != 2 || len(export.Participants) != 1 || len(export.Responses) != 2 {
+		t.Fatalf("export = %+v", export)
+	}
+	encoded, err := json.Marshal(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// インポートしたイベントには新しい ID を振る
+	var imported handlers.ImportEventResponse
+	decodeJSON(t, ts.request(http.MethodPost, "/api/v1/events/import", export, bearer(token)), http.StatusCreated, &imported)
+	if imported.ID == "" || imported.ID == eventID {
+		t.Fatalf("imported id = %q, want a new id", imported.ID)
+	}
+	event := ts.getEvent(imported.ID, bearer(token))
+	if event.Title != "Team lunch" || len(event.CandidateDates) != 2 || len(event.Participants) != 1 || len(event.Participants[0].Responses) != 2 {
+		t.Fatalf("imported event = %+v", event)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*handlers.EventExport)
+		field  string
+	}{
+		{"unsupported version", func(e *handlers.EventExport) { e.Version = handlers.EventExportVersion + 1 }, "version"},
+		{"duplicate candidate date", func(e *handlers.EventExport) { e.CandidateDates[1].ID = e.CandidateDates[0].ID }, "candidate_dates[1].id"},
+		{"unknown participant", func(e *handlers.EventExport) { e.Responses[0].ParticipantID = 99 }, "responses[0].participant_id"},
+		{"unknown candidate date", func(e *handlers.EventExport) { e.Responses[0].CandidateDateID = 99 }, "responses[0].candidate_date_id"},
+		{"same date answered twice", func(e *handlers.EventExport) { e.Responses[1].CandidateDateID = e.Responses[0].CandidateDateID }, "responses[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var modified handlers.EventExport
+			if err := json.Unmarshal(encoded, &modified); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&modified)
+			resp := ts.request(http.MethodPost, "/api/v1/events/import", modified, bearer(token))
+			apiErr := expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed)
+			if !slices.ContainsFunc(apiErr.Details, func(fe handlers.FieldError) bool { return fe.Field == tt.field }) {
+				t.Errorf("details = %+v, want an error for %s", apiErr.Details, tt.field)
+			}
+		})
+	}
+
+	// ID を保持したインポートは CLI からだけ行える
+	expectError(t, ts.request(http.MethodPost, "/api/v1/events/import?preserve_ids=true", export, bearer(token)), http.StatusBadRequest, handlers.CodeValidationFailed)
+	expectError(t, ts.request(http.MethodPost, "/api/v1/events/import", export, nil), http.StatusUnauthorized, handlers.CodeUnauthorized)
+
+	// ワークスペースへのインポートはオーナーとオーガナイザーだけ
+	member := ts.signUp("member@example.com", "Member")
+	workspaceID := ts.createWorkspace(token, "Team")
+	ts.addMember(token, workspaceID, "member@example.com", "member")
+	expectError(t, ts.request(http.MethodPost, "/api/v1/events/import?workspace_id="+workspaceID, export, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+	decodeJSON(t, ts.request(http.MethodPost, "/api/v1/events/import?workspace_id="+workspaceID, export, bearer(token)), http.StatusCreated, &imported)
+
+	// ワークスペースのイベントはメンバー以外には書き出せない
+	outsider := ts.signUp("outsider@example.com", "Outsider")
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events/"+imported.ID+"/export", nil, bearer(outsider)), http.StatusNotFound, handlers.CodeEventNotFound)
+	decodeJSON(t, ts.request(http.MethodGet, "/api/v1/events/"+imported.ID+"/export", nil, bearer(member)), http.StatusOK, nil)
+}
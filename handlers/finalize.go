@@ -1,85 +1,106 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 	"time"
-	"yotei-backend/database"
+	"yotei-backend/metrics"
 	"yotei-backend/models"
-)
+	"yotei-backend/store"
+	"yotei-backend/telemetry"
 
-func CheckDeadlinesAndFinalize() error {
-	log.Println("Checking and finalizing deadlines...")
+	"go.opentelemetry.io/otel/attribute"
+)
 
-	var events []models.Event
-	if err := database.DB.Find(&events).Error; err != nil {
+func (h *Handler) CheckDeadlinesAndFinalize(ctx context.Context) error {
+	events, err := h.store.ListEventsAwaitingDeadline(ctx)
+	if err != nil {
 		return fmt.Errorf("Failed to get events: %w", err)
 	}
+	slog.DebugContext(ctx, "Checking deadlines", "events", len(events))
 	for _, event := range events {
+		// 停止処理中はキャンセルされるので、次のイベントに進まずに終了する
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if event.DeadlineEnable && event.Deadline != nil && event.Deadline.Before(time.Now()) && !event.DeadlineReached {
-			log.Println("Event deadline reached:", event.ID)
-			decidedCandidateDates, err := mostVotedCandidates(event.ID)
-			if err != nil {
-				return fmt.Errorf("Failed to decide event schedule: %w", err)
+			if err := h.finalizeAtDeadline(ctx, event); err != nil {
+				return err
 			}
+		}
+	}
 
-			var rssFeed models.RSSFeed
-			if len(decidedCandidateDates) == 0 {
-				rssFeed = models.RSSFeed{
-					EventID:     event.ID,
-					Title:       event.Title,
-					Link:        fmt.Sprintf("%s/%s/vote", os.Getenv("FRONTEND_URL"), event.ID),
-					Description: fmt.Sprintf("【%s】設定された締切時刻になりましたが、投票がありませんでした。", event.Title),
-					CreatedAt:   time.Now(),
-				}
-			} else if len(decidedCandidateDates) == 1 {
-				decidedCandidateDate := decidedCandidateDates[0]
-				log.Println("Decided candidate date:", decidedCandidateDate.ID)
-				rssFeed = models.RSSFeed{
-					EventID:     event.ID,
-					Title:       event.Title,
-					Link:        fmt.Sprintf("%s/%s/vote", os.Getenv("FRONTEND_URL"), event.ID),
-					Description: fmt.Sprintf("【%s】設定された締切時刻になりました。最も投票が多かった予定日はこちらです。\n予定日: %s", event.Title, decidedCandidateDate.DateTime.Format("2006年01月02日")),
-					CreatedAt:   time.Now(),
-				}
-			} else {
-				dates := ""
-				for i, decidedCandidateDate := range decidedCandidateDates {
-					if i != 0 {
-						dates += ", "
-					}
-					dates += decidedCandidateDate.DateTime.Format("2006年01月02日")
-				}
-				rssFeed = models.RSSFeed{
-					EventID:     event.ID,
-					Title:       event.Title,
-					Link:        fmt.Sprintf("%s/%s/vote", os.Getenv("FRONTEND_URL"), event.ID),
-					Description: fmt.Sprintf("【%s】設定された締切時刻になりましたが、最も投票が多かった予定日が複数存在します。\n予定日: %s", event.Title, dates),
-					CreatedAt:   time.Now(),
-				}
-			}
+	return nil
+}
 
-			if err := database.DB.Create(&rssFeed).Error; err != nil {
-				return fmt.Errorf("Failed to create RSS feed: %w", err)
-			}
-			event.DeadlineReached = true
-			if err := database.DB.Save(&event).Error; err != nil {
-				return fmt.Errorf("Failed to update event: %w", err)
+func (h *Handler) finalizeAtDeadline(ctx context.Context, event models.Event) (err error) {
+	ctx, span := telemetry.Start(ctx, "finalize deadline", attribute.String("event.id", event.ID))
+	defer func() { telemetry.End(span, err) }()
+
+	decidedCandidateDates, err := h.mostVotedCandidates(ctx, event.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to decide event schedule for event %s: %w", event.ID, err)
+	}
+
+	var rssFeed models.RSSFeed
+	if len(decidedCandidateDates) == 0 {
+		rssFeed = models.RSSFeed{
+			EventID:     event.ID,
+			Title:       event.Title,
+			Link:        h.voteURL(event.ID),
+			Description: fmt.Sprintf("【%s】設定された締切時刻になりましたが、投票がありませんでした。", event.Title),
+			CreatedAt:   time.Now(),
+		}
+	} else if len(decidedCandidateDates) == 1 {
+		decidedCandidateDate := decidedCandidateDates[0]
+		rssFeed = models.RSSFeed{
+			EventID:     event.ID,
+			Title:       event.Title,
+			Link:        h.voteURL(event.ID),
+			Description: fmt.Sprintf("【%s】設定された締切時刻になりました。最も投票が多かった予定日はこちらです。\n予定日: %s", event.Title, decidedCandidateDate.DateTime.Format("2006年01月02日")),
+			CreatedAt:   time.Now(),
+		}
+	} else {
+		dates := ""
+		for i, decidedCandidateDate := range decidedCandidateDates {
+			if i != 0 {
+				dates += ", "
 			}
+			dates += decidedCandidateDate.DateTime.Format("2006年01月02日")
+		}
+		rssFeed = models.RSSFeed{
+			EventID:     event.ID,
+			Title:       event.Title,
+			Link:        h.voteURL(event.ID),
+			Description: fmt.Sprintf("【%s】設定された締切時刻になりましたが、最も投票が多かった予定日が複数存在します。\n予定日: %s", event.Title, dates),
+			CreatedAt:   time.Now(),
+		}
+	}
+
+	if err := h.store.RecordDecision(ctx, event.ID, store.DecisionDeadline, &rssFeed); err != nil {
+		// 別のレプリカが先に締切処理を済ませた
+		if errors.Is(err, store.ErrConflict) {
+			return nil
 		}
+		return fmt.Errorf("Failed to record decision for event %s: %w", event.ID, err)
 	}
+	logDecision(ctx, "deadline", event.ID, decidedCandidateDates)
 
 	return nil
 }
 
-func CheckAutoDecisionAndFinalize(eventID string) error {
-	var event models.Event
-	if err := database.DB.First(&event, "id = ?", eventID).Error; err != nil {
+func (h *Handler) CheckAutoDecisionAndFinalize(ctx context.Context, eventID string) (err error) {
+	ctx, span := telemetry.Start(ctx, "finalize auto decision", attribute.String("event.id", eventID))
+	defer func() { telemetry.End(span, err) }()
+
+	event, err := h.store.GetEvent(ctx, eventID)
+	if err != nil {
 		return fmt.Errorf("Failed to get event: %w", err)
 	}
 
-	decidedCandidateDates, err := mostVotedCandidates(eventID)
+	decidedCandidateDates, err := h.mostVotedCandidates(ctx, eventID)
 	if err != nil {
 		return fmt.Errorf("Failed to get most voted candidates: %w", err)
 	}
@@ -89,7 +110,7 @@ func CheckAutoDecisionAndFinalize(eventID string) error {
 			rssFeed = models.RSSFeed{
 				EventID:     eventID,
 				Title:       event.Title,
-				Link:        fmt.Sprintf("%s/%s/vote", os.Getenv("FRONTEND_URL"), eventID),
+				Link:        h.voteURL(eventID),
 				Description: fmt.Sprintf("【%s】%d人以上の投票が集まりましたが、投票日は一日もありませんでした。", event.Title, event.AutoDecisionThreshold),
 				CreatedAt:   time.Now(),
 			}
@@ -98,7 +119,7 @@ func CheckAutoDecisionAndFinalize(eventID string) error {
 			rssFeed = models.RSSFeed{
 				EventID:     eventID,
 				Title:       event.Title,
-				Link:        fmt.Sprintf("%s/%s/vote", os.Getenv("FRONTEND_URL"), eventID),
+				Link:        h.voteURL(eventID),
 				Description: fmt.Sprintf("【%s】%d人以上の投票が集まりました。最も投票が多かった予定日はこちらです。\n予定日: %s", event.Title, event.AutoDecisionThreshold, decidedCandidateDate.DateTime.Format("2006年01月02日")),
 				CreatedAt:   time.Now(),
 			}
@@ -113,49 +134,81 @@ func CheckAutoDecisionAndFinalize(eventID string) error {
 			rssFeed = models.RSSFeed{
 				EventID:     eventID,
 				Title:       event.Title,
-				Link:        fmt.Sprintf("%s/%s/vote", os.Getenv("FRONTEND_URL"), eventID),
+				Link:        h.voteURL(eventID),
 				Description: fmt.Sprintf("【%s】%d人以上の投票が集まりましたが、最も投票が多かった予定日が複数存在します。\n予定日: %s", event.Title, event.AutoDecisionThreshold, dates),
 				CreatedAt:   time.Now(),
 			}
 		}
-		if err := database.DB.Create(&rssFeed).Error; err != nil {
-			return fmt.Errorf("Failed to create RSS feed: %w", err)
-		}
-		event.AutoDecisionReached = true
-		if err := database.DB.Save(&event).Error; err != nil {
-			return fmt.Errorf("Failed to update event: %w", err)
+		if err := h.store.RecordDecision(ctx, eventID, store.DecisionAutoDecision, &rssFeed); err != nil {
+			// 同時に登録した別の参加者のリクエストが先に決定した
+			if errors.Is(err, store.ErrConflict) {
+				return nil
+			}
+			return fmt.Errorf("Failed to record decision: %w", err)
 		}
+		logDecision(ctx, "auto_decision", eventID, decidedCandidateDates)
 	}
 
 	return nil
 }
 
-func mostVotedCandidates(eventID string) ([]models.CandidateDate, error) {
-	var event models.Event
-	if err := database.DB.Preload("Participants").Preload("CandidateDates").Preload("CandidateDates.Responses").First(&event, "id = ?", eventID).Error; err != nil {
-		return []models.CandidateDate{}, fmt.Errorf("Failed to get event: %w", err)
+// logDecision は確定結果をメトリクスとログに記録する
+func logDecision(ctx context.Context, trigger string, eventID string, decidedCandidateDates []models.CandidateDate) {
+	outcome := decisionOutcome(decidedCandidateDates)
+	metrics.Finalizations.WithLabelValues(trigger, outcome).Inc()
+
+	candidateDateIDs := make([]uint, len(decidedCandidateDates))
+	for i, candidateDate := range decidedCandidateDates {
+		candidateDateIDs[i] = candidateDate.ID
+	}
+	slog.InfoContext(ctx, "Event finalized",
+		"event_id", eventID, "trigger", trigger, "outcome", outcome, "candidate_date_ids", candidateDateIDs)
+}
+
+func decisionOutcome(decidedCandidateDates []models.CandidateDate) string {
+	switch len(decidedCandidateDates) {
+	case 0:
+		return "no_votes"
+	case 1:
+		return "single"
+	default:
+		return "tie"
+	}
+}
+
+func (h *Handler) mostVotedCandidates(ctx context.Context, eventID string) ([]models.CandidateDate, error) {
+	tallies, err := h.store.TallyResponses(ctx, eventID)
+	if err != nil {
+		return []models.CandidateDate{}, fmt.Errorf("Failed to count responses: %w", err)
 	}
 
-	maxScore := 0
 	decidedCandidateDates := []models.CandidateDate{}
-	for _, candidateDate := range event.CandidateDates {
-		score := 0
-		for _, response := range candidateDate.Responses {
-			if response.Status == "available" {
-				score++
-			}
-		}
+	for _, tally := range leadingCandidates(tallies) {
+		decidedCandidateDates = append(decidedCandidateDates, models.CandidateDate{
+			ID:       tally.CandidateDateID,
+			EventID:  eventID,
+			DateTime: tally.DateTime,
+		})
+	}
+	return decidedCandidateDates, nil
+}
+
+// leadingCandidates は参加可能の回答が最も多い候補日を返す。同数の場合はすべて、誰も参加できない場合は空
+func leadingCandidates(tallies []store.CandidateDateTally) []store.CandidateDateTally {
+	var maxScore int64
+	leaders := []store.CandidateDateTally{}
+	for _, tally := range tallies {
+		score := tally.Available
 		if score == 0 {
 			continue
 		}
 
 		if score > maxScore {
 			maxScore = score
-			decidedCandidateDates = []models.CandidateDate{candidateDate}
+			leaders = []store.CandidateDateTally{tally}
 		} else if score == maxScore {
-			decidedCandidateDates = append(decidedCandidateDates, candidateDate)
+			leaders = append(leaders, tally)
 		}
 	}
-
-	return decidedCandidateDates, nil
+	return leaders
 }
@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"yotei-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// feedDescriptions は RSS の通知の本文を作成順に返す
+func (ts *testServer) feedDescriptions(eventID string) []string {
+	ts.t.Helper()
+
+	feeds, err := ts.store.ListFeeds(context.Background(), eventID)
+	if err != nil {
+		ts.t.Fatalf("list feeds: %v", err)
+	}
+	descriptions := make([]string, len(feeds))
+	for i, feed := range feeds {
+		descriptions[i] = feed.Description
+	}
+	return descriptions
+}
+
+func TestAutoDecision(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	req := eventRequest("Team lunch", first, first.AddDate(0, 0, 1))
+	req["settings"] = map[string]any{
+		"allow_setting_changes":   true,
+		"auto_decision_enable":    true,
+		"auto_decision_threshold": 2,
+		"rss_enabled":             true,
+	}
+	eventID := ts.createEvent(req, nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "Alice", ids, nil), nil), http.StatusCreated, nil)
+	if event := ts.getEvent(eventID, nil); event.AutoDecisionReached {
+		t.Fatal("decided before reaching the threshold")
+	}
+
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 2, "Bob", ids[:1], ids[1:]), nil), http.StatusCreated, nil)
+	if event := ts.getEvent(eventID, nil); !event.AutoDecisionReached {
+		t.Fatal("auto_decision_reached = false after reaching the threshold")
+	}
+	descriptions := ts.feedDescriptions(eventID)
+	if len(descriptions) != 1 || !strings.Contains(descriptions[0], "最も投票が多かった予定日はこちらです") ||
+		!strings.Contains(descriptions[0], "2030年01月10日") {
+		t.Fatalf("feeds = %q", descriptions)
+	}
+
+	// 決定済みのイベントは参加者が増えても通知を重ねない
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 3, "Carol", nil, ids), nil), http.StatusCreated, nil)
+	if descriptions := ts.feedDescriptions(eventID); len(descriptions) != 1 {
+		t.Fatalf("feeds after another vote = %q", descriptions)
+	}
+
+	resp := ts.request(http.MethodGet, "/api/v1/rss/"+eventID+"/feed", nil, nil)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || strings.Count(string(body), "<item>") != 1 {
+		t.Fatalf("status = %d, feed:\n%s", resp.StatusCode, body)
+	}
+}
+
+func TestAutoDecisionTie(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	req := eventRequest("Team lunch", first, first.AddDate(0, 0, 1))
+	req["settings"] = map[string]any{"auto_decision_enable": true, "auto_decision_threshold": 2}
+	eventID := ts.createEvent(req, nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 1, "Alice", ids[:1], ids[1:]), nil), http.StatusCreated, nil)
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), vote(eventID, 2, "Bob", ids[1:], ids[:1]), nil), http.StatusCreated, nil)
+
+	descriptions := ts.feedDescriptions(eventID)
+	if len(descriptions) != 1 || !strings.Contains(descriptions[0], "複数存在します") ||
+		!strings.Contains(descriptions[0], "2030年01月10日, 2030年01月11日") {
+		t.Fatalf("feeds = %q", descriptions)
+	}
+}
+
+// createEventWithDeadline は締切を過去にできるよう、ストアに直接イベントを作成する
+func (ts *testServer) createEventWithDeadline(deadline time.Time, dates ...time.Time) string {
+	ts.t.Helper()
+
+	event := models.Event{
+		ID:                  uuid.New().String(),
+		Title:               "Team lunch",
+		AllowSettingChanges: true,
+		DeadlineEnable:      true,
+		Deadline:            &deadline,
+	}
+	for _, date := range dates {
+		event.CandidateDates = append(event.CandidateDates, models.CandidateDate{EventID: event.ID, DateTime: date})
+	}
+	if err := ts.store.CreateEvent(context.Background(), &event); err != nil {
+		ts.t.Fatalf("create event: %v", err)
+	}
+	return event.ID
+}
+
+func TestCheckDeadlinesAndFinalize(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	ctx := context.Background()
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	passedID := ts.createEventWithDeadline(time.Now().Add(-time.Minute), first, first.AddDate(0, 0, 1))
+	noVotesID := ts.createEventWithDeadline(time.Now().Add(-time.Minute), first)
+	upcomingID := ts.createEventWithDeadline(time.Now().Add(time.Hour), first)
+
+	ids := candidateDateIDs(t, ts.getEvent(passedID, nil))
+	decodeJSON(t, ts.request(http.MethodPost, participantPath(passedID), vote(passedID, 1, "Alice", ids[1:], ids[:1]), nil), http.StatusCreated, nil)
+
+	if err := ts.handler.CheckDeadlinesAndFinalize(ctx); err != nil {
+		t.Fatalf("CheckDeadlinesAndFinalize: %v", err)
+	}
+
+	if event := ts.getEvent(passedID, nil); !event.DeadlineReached {
+		t.Error("deadline_reached = false for a passed deadline")
+	}
+	descriptions := ts.feedDescriptions(passedID)
+	if len(descriptions) != 1 || !strings.Contains(descriptions[0], "最も投票が多かった予定日はこちらです") ||
+		!strings.Contains(descriptions[0], "2030年01月11日") {
+		t.Errorf("feeds = %q", descriptions)
+	}
+
+	if descriptions := ts.feedDescriptions(noVotesID); len(descriptions) != 1 || !strings.Contains(descriptions[0], "投票がありませんでした") {
+		t.Errorf("feeds without votes = %q", descriptions)
+	}
+
+	if event := ts.getEvent(upcomingID, nil); event.DeadlineReached {
+		t.Error("deadline_reached = true before the deadline")
+	}
+	if descriptions := ts.feedDescriptions(upcomingID); len(descriptions) != 0 {
+		t.Errorf("feeds before the deadline = %q", descriptions)
+	}
+
+	// 締切処理済みのイベントは再び通知しない
+	if err := ts.handler.CheckDeadlinesAndFinalize(ctx); err != nil {
+		t.Fatalf("CheckDeadlinesAndFinalize: %v", err)
+	}
+	if descriptions := ts.feedDescriptions(passedID); len(descriptions) != 1 {
+		t.Errorf("feeds after the second run = %q", descriptions)
+	}
+}
+
+func TestCheckDeadlinesAndFinalizeCancelled(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	eventID := ts.createEventWithDeadline(time.Now().Add(-time.Minute), time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ts.handler.CheckDeadlinesAndFinalize(ctx); err == nil {
+		t.Fatal("CheckDeadlinesAndFinalize with a cancelled context returned nil")
+	}
+	if event := ts.getEvent(eventID, nil); event.DeadlineReached {
+		t.Error("finalized after cancellation")
+	}
+}
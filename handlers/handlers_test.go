@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"yotei-backend/config"
+	"yotei-backend/handlers"
+	"yotei-backend/models"
+	"yotei-backend/scheduler"
+	"yotei-backend/server"
+	"yotei-backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// testServer はメモリのストアでハンドラを動かす。ルートは server.New で登録し、レート制限は付けない
+type testServer struct {
+	t       *testing.T
+	app     *fiber.App
+	store   *store.MemoryStore
+	handler *handlers.Handler
+}
+
+func testConfig() handlers.Config {
+	return handlers.Config{
+		FrontendURL:    "https://yotei.example.com",
+		TimeZone:       time.UTC,
+		SessionTTL:     time.Hour,
+		CookieSameSite: fiber.CookieSameSiteLaxMode,
+	}
+}
+
+// healthyChecks は依存先がすべて正常な場合のチェック
+func healthyChecks() handlers.HealthChecks {
+	return handlers.HealthChecks{
+		Ping:             func(ctx context.Context) error { return nil },
+		MigrationVersion: func(ctx context.Context) (int, int, error) { return 1, 1, nil },
+		Scheduler:        func() scheduler.Status { return scheduler.Status{} },
+		Timeout:          time.Second,
+	}
+}
+
+func newTestServer(t *testing.T, cfg handlers.Config) *testServer {
+	t.Helper()
+	return newTestServerWithHealth(t, cfg, healthyChecks())
+}
+
+func newTestServerWithHealth(t *testing.T, cfg handlers.Config, health handlers.HealthChecks) *testServer {
+	t.Helper()
+
+	s := store.NewMemoryStore()
+	h := handlers.New(s, cfg)
+	// ゼロ値の設定ではレート制限の Limit が 0 なので制限しない
+	app := server.New(config.Config{}, h, health)
+
+	return &testServer{t: t, app: app, store: s, handler: h}
+}
+
+// request はリクエストを送る。body が nil でなければ JSON にして送る
+func (ts *testServer) request(method, path string, body any, headers map[string]string) *http.Response {
+	ts.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			ts.t.Fatalf("marshal request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := ts.app.Test(req, -1)
+	if err != nil {
+		ts.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return resp
+}
+
+// signUp はユーザーを登録してセッショントークンを返す
+func (ts *testServer) signUp(email, name string) string {
+	ts.t.Helper()
+
+	resp := ts.request(http.MethodPost, "/api/v1/auth/signup", map[string]string{
+		"email":    email,
+		"password": "password1234",
+		"name":     name,
+	}, nil)
+	var auth handlers.AuthResponse
+	decodeJSON(ts.t, resp, http.StatusCreated, &auth)
+	return auth.Token
+}
+
+func bearer(token string) map[string]string {
+	return map[string]string{fiber.HeaderAuthorization: "Bearer " + token}
+}
+
+// decodeJSON はステータスコードを確認して本文を v に読み込む
+func decodeJSON(t *testing.T, resp *http.Response, status int, v any) {
+	t.Helper()
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response body: %v", err)
+	}
+	if resp.StatusCode != status {
+		t.Fatalf("status = %d, want %d; body: %s", resp.StatusCode, status, body)
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("decode response body %s: %v", body, err)
+	}
+}
+
+// expectError はエラーレスポンスのステータスコードとコードを確認する
+func expectError(t *testing.T, resp *http.Response, status int, code string) *handlers.APIError {
+	t.Helper()
+
+	var body struct {
+		Error handlers.APIError `json:"error"`
+	}
+	decodeJSON(t, resp, status, &body)
+	if body.Error.Code != code {
+		t.Fatalf("error code = %q, want %q (message: %s)", body.Error.Code, code, body.Error.Message)
+	}
+	return &body.Error
+}
+
+func rfc3339(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// createEvent はイベントを作成して ID を返す
+func (ts *testServer) createEvent(req map[string]any, headers map[string]string) string {
+	ts.t.Helper()
+
+	resp := ts.request(http.MethodPost, "/api/v1/events", req, headers)
+	var created handlers.CreateEventResponse
+	decodeJSON(ts.t, resp, http.StatusCreated, &created)
+	if created.ID == "" {
+		ts.t.Fatal("created event has no id")
+	}
+	return created.ID
+}
+
+// getEvent は候補日・参加者を含むイベントを取得する
+func (ts *testServer) getEvent(eventID string, headers map[string]string) models.Event {
+	ts.t.Helper()
+
+	var event models.Event
+	decodeJSON(ts.t, ts.request(http.MethodGet, "/api/v1/events/"+eventID, nil, headers), http.StatusOK, &event)
+	return event
+}
+
+func eventRequest(title string, dates ...time.Time) map[string]any {
+	candidateDates := make([]string, len(dates))
+	for i, date := range dates {
+		candidateDates[i] = rfc3339(date)
+	}
+	return map[string]any{
+		"title":           title,
+		"creator_name":    "Organizer",
+		"candidate_dates": candidateDates,
+		"settings": map[string]any{
+			"allow_setting_changes": true,
+		},
+	}
+}
+
+// vote は participantID の参加者として available の候補日に参加可能、それ以外に参加不可で回答する
+func vote(eventID string, participantID uint, name string, available []uint, unavailable []uint) map[string]any {
+	ids := func(values []uint) []map[string]uint {
+		out := make([]map[string]uint, len(values))
+		for i, value := range values {
+			out[i] = map[string]uint{"id": value}
+		}
+		return out
+	}
+	return map[string]any{
+		"event_id":                    eventID,
+		"participant_id":              participantID,
+		"name":                        name,
+		"available_candidate_dates":   ids(available),
+		"unavailable_candidate_dates": ids(unavailable),
+	}
+}
+
+func participantPath(eventID string) string {
+	return fmt.Sprintf("/api/v1/events/%s/participant", eventID)
+}
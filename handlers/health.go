@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"yotei-backend/scheduler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthChecks はロードバランサや監視向けのエンドポイントが使う依存先のチェック
+type HealthChecks struct {
+	Ping             func(ctx context.Context) error
+	MigrationVersion func(ctx context.Context) (current int, latest int, err error)
+	Scheduler        func() scheduler.Status
+
+	// データベースへの問い合わせのタイムアウト
+	Timeout time.Duration
+}
+
+// CheckResult は依存先のチェック結果。認証なしで公開するため、エラーの内容はログにだけ出す
+type CheckResult struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+type MigrationCheckResult struct {
+	CheckResult
+	Version       int `json:"version"`
+	LatestVersion int `json:"latest_version"`
+}
+
+type ReadinessResponse struct {
+	Status     string               `json:"status"`
+	Database   CheckResult          `json:"database"`
+	Migrations MigrationCheckResult `json:"migrations"`
+}
+
+type StatusResponse struct {
+	Status           string           `json:"status"`
+	MigrationVersion int              `json:"migration_version"`
+	Scheduler        scheduler.Status `json:"scheduler"`
+}
+
+// Livez はプロセスが応答できるかだけを返す。依存先はチェックしない
+func (hc HealthChecks) Livez(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status": "ok",
+	})
+}
+
+// Readyz はデータベースに接続でき、スキーマが最新の場合のみ 200 を返す
+func (hc HealthChecks) Readyz(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), hc.Timeout)
+	defer cancel()
+
+	response := ReadinessResponse{Status: "ready"}
+
+	started := time.Now()
+	response.Database = checkResult(ctx, "database", hc.Ping(ctx), started)
+
+	started = time.Now()
+	current, latest, err := hc.MigrationVersion(ctx)
+	response.Migrations = MigrationCheckResult{
+		CheckResult:   checkResult(ctx, "migrations", err, started),
+		Version:       current,
+		LatestVersion: latest,
+	}
+	if err == nil && current < latest {
+		response.Migrations.Status = "pending"
+	}
+
+	if response.Database.Status != "ok" || response.Migrations.Status != "ok" {
+		response.Status = "unavailable"
+		return c.Status(fiber.StatusServiceUnavailable).JSON(response)
+	}
+	return c.JSON(response)
+}
+
+// Status はスケジューラの直近の実行結果を返す。直近の実行が失敗したか、ジョブがタイムアウトを過ぎても終わらない場合は degraded
+func (hc HealthChecks) Status(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), hc.Timeout)
+	defer cancel()
+
+	response := StatusResponse{
+		Status:    "ok",
+		Scheduler: hc.Scheduler(),
+	}
+	if current, _, err := hc.MigrationVersion(ctx); err == nil {
+		response.MigrationVersion = current
+	} else {
+		slog.WarnContext(ctx, "Status check failed", "check", "migrations", "error", err)
+	}
+	lastRunFailed := response.Scheduler.LastErrorAt != nil &&
+		(response.Scheduler.LastSuccessAt == nil || response.Scheduler.LastErrorAt.After(*response.Scheduler.LastSuccessAt))
+	if lastRunFailed || response.Scheduler.Stuck {
+		response.Status = "degraded"
+	}
+	return c.JSON(response)
+}
+
+func checkResult(ctx context.Context, check string, err error, started time.Time) CheckResult {
+	result := CheckResult{Status: "ok", LatencyMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		slog.WarnContext(ctx, "Readiness check failed", "check", check, "error", err)
+		result.Status = "error"
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"yotei-backend/scheduler"
+)
+
+func TestReadyzHidesErrorDetails(t *testing.T) {
+	health := healthyChecks()
+	health.Ping = func(ctx context.Context) error {
+		return errors.New(`dial tcp 10.0.0.5:5432: password authentication failed for user "yotei"`)
+	}
+	ts := newTestServerWithHealth(t, testConfig(), health)
+
+	resp := ts.request(http.MethodGet, "/readyz", nil, nil)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503; body: %s", resp.StatusCode, body)
+	}
+	if strings.Contains(string(body), "10.0.0.5") || strings.Contains(string(body), "password") {
+		t.Errorf("readyz leaks the error: %s", body)
+	}
+	if !strings.Contains(string(body), `"database":{"status":"error"`) {
+		t.Errorf("readyz body = %s, want database error", body)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	started := time.Now().Add(-time.Hour)
+	succeeded := started.Add(-time.Minute)
+	failed := started.Add(-2 * time.Minute)
+
+	tests := []struct {
+		name      string
+		scheduler scheduler.Status
+		want      string
+	}{
+		{"never run", scheduler.Status{}, "ok"},
+		{"succeeded after failure", scheduler.Status{LastSuccessAt: &succeeded, LastErrorAt: &failed}, "ok"},
+		{"failed", scheduler.Status{LastErrorAt: &failed}, "degraded"},
+		{"running", scheduler.Status{Running: true, RunningSince: &started}, "ok"},
+		{"stuck", scheduler.Status{Running: true, RunningSince: &started, Stuck: true}, "degraded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := healthyChecks()
+			health.Scheduler = func() scheduler.Status { return tt.scheduler }
+			ts := newTestServerWithHealth(t, testConfig(), health)
+
+			var got struct {
+				Status string `json:"status"`
+			}
+			decodeJSON(t, ts.request(http.MethodGet, "/status", nil, nil), http.StatusOK, &got)
+			if got.Status != tt.want {
+				t.Errorf("status = %q, want %q", got.Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"yotei-backend/models"
+	"yotei-backend/oidcauth"
+	"yotei-backend/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	oidcCookieName = "yotei_oidc"
+	oidcCookiePath = "/api/v1/auth/oidc"
+	// プロバイダでのログインにかけられる時間
+	oidcFlowTTL = 10 * time.Minute
+)
+
+// OIDCProvider は OpenID Connect の認可コードフロー。oidcauth.Client が実装する
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidcauth.Claims, error)
+}
+
+// oidcFlow はログイン開始からコールバックまでの間 Cookie に保存する値
+type oidcFlow struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	Redirect     string `json:"redirect"`
+}
+
+func oidcError(message string, err error) *APIError {
+	return &APIError{Status: fiber.StatusUnauthorized, Code: CodeOIDCFailed, Message: message, Err: err}
+}
+
+// safeRedirect はログイン後の戻り先として、フロントエンド内の相対パスだけを許可する
+func safeRedirect(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.ContainsAny(path, "\\\r\n") {
+		return "/"
+	}
+	return path
+}
+
+// OIDCLogin は state・nonce・PKCE の検証値を Cookie に保存し、プロバイダのログイン画面にリダイレクトする
+func (h *Handler) OIDCLogin(c *fiber.Ctx) error {
+	if h.config.OIDC == nil {
+		return ErrOIDCDisabled
+	}
+
+	flow := oidcFlow{Redirect: safeRedirect(c.Query("redirect"))}
+	for _, value := range []*string{&flow.State, &flow.Nonce, &flow.CodeVerifier} {
+		token, err := randomToken()
+		if err != nil {
+			return internalError("Failed to generate OIDC state", err)
+		}
+		*value = token
+	}
+
+	authURL, err := h.config.OIDC.AuthCodeURL(c.UserContext(), flow.State, flow.Nonce, flow.CodeVerifier)
+	if err != nil {
+		return &APIError{Status: fiber.StatusBadGateway, Code: CodeOIDCFailed, Message: "Failed to contact the identity provider", Err: err}
+	}
+
+	encoded, err := json.Marshal(flow)
+	if err != nil {
+		return internalError("Failed to encode OIDC state", err)
+	}
+	// プロバイダからのリダイレクト（別サイトからの遷移）でも送られるよう SameSite は Lax にする
+	h.setOIDCCookie(c, base64.RawURLEncoding.EncodeToString(encoded), time.Now().Add(oidcFlowTTL))
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback は認可コードを検証してユーザーを読み込み（初回は作成し）、セッションを開始してフロントエンドに戻す
+func (h *Handler) OIDCCallback(c *fiber.Ctx) error {
+	if h.config.OIDC == nil {
+		return ErrOIDCDisabled
+	}
+
+	flow, err := readOIDCFlow(c.Cookies(oidcCookieName))
+	h.setOIDCCookie(c, "", time.Unix(0, 0))
+	if err != nil {
+		return oidcError("Login session expired or is invalid; please try again", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(flow.State)) != 1 {
+		return oidcError("State does not match", nil)
+	}
+	if idpError := c.Query("error"); idpError != "" {
+		return oidcError("The identity provider returned an error: "+idpError, nil)
+	}
+	if c.Query("code") == "" {
+		return oidcError("Authorization code is missing", nil)
+	}
+
+	ctx := c.UserContext()
+	claims, err := h.config.OIDC.Exchange(ctx, c.Query("code"), flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		return oidcError("Failed to verify the login with the identity provider", err)
+	}
+
+	user, err := h.userForClaims(ctx, claims)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := h.createSession(c, user); err != nil {
+		return err
+	}
+	return c.Redirect(strings.TrimSuffix(h.config.FrontendURL, "/")+flow.Redirect, fiber.StatusFound)
+}
+
+// userForClaims はプロバイダのアカウントに紐づいたユーザーを返す。
+// 未登録の場合は確認済みのメールアドレスが一致するユーザーに紐づけ、それもなければパスワードなしのユーザーを作成する
+func (h *Handler) userForClaims(ctx context.Context, claims *oidcauth.Claims) (*models.User, error) {
+	user, err := h.store.GetUserByIdentity(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, internalError("Failed to get user", err)
+	}
+
+	email := normalizeEmail(claims.Email)
+	if email == "" {
+		return nil, oidcError("The identity provider did not return an email address", nil)
+	}
+
+	user = nil
+	if claims.EmailVerified {
+		user, err = h.store.GetUserByEmail(ctx, email)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, internalError("Failed to get user", err)
+		}
+		// パスワードで登録したアカウントはメールアドレスを確認していないので紐づけない。
+		// 他人のメールアドレスで先に登録しておき、本人が IdP でログインした後に乗っ取る攻撃を防ぐ
+		if user != nil && user.PasswordHash != "" {
+			return nil, ErrEmailTaken
+		}
+	}
+	if user == nil {
+		name := claims.Name
+		if name == "" {
+			name, _, _ = strings.Cut(email, "@")
+		}
+		if len([]rune(name)) > 100 {
+			name = string([]rune(name)[:100])
+		}
+		user = &models.User{ID: uuid.New().String(), Email: email, Name: name}
+		if err := h.store.CreateUser(ctx, user); err != nil {
+			// 未確認のメールアドレスで既存のアカウントを乗っ取れないようにする
+			if errors.Is(err, store.ErrConflict) {
+				return nil, ErrEmailTaken
+			}
+			return nil, internalError("Failed to create user", err)
+		}
+	}
+
+	identity := models.UserIdentity{UserID: user.ID, Issuer: claims.Issuer, Subject: claims.Subject, Email: email}
+	if err := h.store.CreateIdentity(ctx, &identity); err != nil {
+		return nil, internalError("Failed to link identity", err)
+	}
+	return user, nil
+}
+
+func readOIDCFlow(cookie string) (*oidcFlow, error) {
+	if cookie == "" {
+		return nil, errors.New("OIDC state cookie is missing")
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil {
+		return nil, err
+	}
+	var flow oidcFlow
+	if err := json.Unmarshal(decoded, &flow); err != nil {
+		return nil, err
+	}
+	if flow.State == "" || flow.CodeVerifier == "" {
+		return nil, errors.New("OIDC state cookie is incomplete")
+	}
+	return &flow, nil
+}
+
+func (h *Handler) setOIDCCookie(c *fiber.Ctx, value string, expires time.Time) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcCookieName,
+		Value:    value,
+		Path:     oidcCookiePath,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   h.config.CookieSecure,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
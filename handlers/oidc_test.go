@@ -0,0 +1,459 @@
+package handlers_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"yotei-backend/config"
+	"yotei-backend/handlers"
+	"yotei-backend/models"
+	"yotei-backend/oidcauth"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	oidcClientID    = "yotei"
+	oidcRedirectURL = "https://api.yotei.example.com/api/v1/auth/oidc/callback"
+)
+
+// mockIssuer はディスカバリ・JWKS・トークンエンドポイントを持つ OpenID Connect のプロバイダ。
+// ログイン画面は authorize で代わりに通過し、発行した認可コードで ID トークンを返す
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+	// トークンエンドポイントが受け取った PKCE の検証値
+	verifiers []string
+}
+
+type issuedCode struct {
+	challenge string
+	claims    map[string]any
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	issuer := &mockIssuer{t: t, key: key, codes: map[string]issuedCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                issuer.server.URL,
+			"authorization_endpoint":                issuer.server.URL + "/authorize",
+			"token_endpoint":                        issuer.server.URL + "/token",
+			"jwks_uri":                              issuer.server.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("POST /token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// client は mockIssuer を使う oidcauth.Client を返す
+func (m *mockIssuer) client() *oidcauth.Client {
+	return oidcauth.New(config.OIDCConfig{
+		IssuerURL:    m.server.URL,
+		ClientID:     oidcClientID,
+		ClientSecret: "secret",
+		RedirectURL:  oidcRedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+}
+
+// authorize はユーザーがプロバイダでログインしたものとして、認可 URL に対する認可コードを発行する。
+// claims は ID トークンに追加するクレームで、nonce を含めなければ認可 URL の nonce を使う
+func (m *mockIssuer) authorize(authURL string, claims map[string]any) string {
+	m.t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatalf("parse authorization url: %v", err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != m.server.URL+"/authorize" {
+		m.t.Fatalf("authorization endpoint = %s, want %s/authorize", got, m.server.URL)
+	}
+	query := u.Query()
+	for name, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             oidcClientID,
+		"redirect_uri":          oidcRedirectURL,
+		"scope":                 "openid email profile",
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(name); got != want {
+			m.t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if query.Get("state") == "" || query.Get("nonce") == "" || query.Get("code_challenge") == "" {
+		m.t.Fatalf("authorization url lacks state, nonce or code_challenge: %s", authURL)
+	}
+
+	idClaims := map[string]any{"nonce": query.Get("nonce")}
+	for name, value := range claims {
+		idClaims[name] = value
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code := rand.Text()
+	m.codes[code] = issuedCode{challenge: query.Get("code_challenge"), claims: idClaims}
+	return code
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != oidcClientID || secret != "secret" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	verifier := r.PostForm.Get("code_verifier")
+	m.mu.Lock()
+	issued, found := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.verifiers = append(m.verifiers, verifier)
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(verifier))
+	if !found || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != oidcRedirectURL ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != issued.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss": m.server.URL,
+		"aud": oidcClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for name, value := range issued.claims {
+		claims[name] = value
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     m.sign(claims),
+	})
+}
+
+func (m *mockIssuer) sign(claims map[string]any) string {
+	m.t.Helper()
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: m.key, KeyID: "test"}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		m.t.Fatalf("create signer: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		m.t.Fatalf("marshal claims: %v", err)
+	}
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		m.t.Fatalf("sign id_token: %v", err)
+	}
+	token, err := signed.CompactSerialize()
+	if err != nil {
+		m.t.Fatalf("serialize id_token: %v", err)
+	}
+	return token
+}
+
+func (m *mockIssuer) receivedVerifiers() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.verifiers...)
+}
+
+func newOIDCTestServer(t *testing.T) (*testServer, *mockIssuer) {
+	t.Helper()
+
+	issuer := newMockIssuer(t)
+	cfg := testConfig()
+	cfg.OIDC = issuer.client()
+	return newTestServer(t, cfg), issuer
+}
+
+// oidcLogin はログインを開始し、プロバイダの認可 URL と state を保存した Cookie を返す
+func (ts *testServer) oidcLogin(redirect string) (string, *http.Cookie) {
+	ts.t.Helper()
+
+	resp := ts.request(http.MethodGet, "/api/v1/auth/oidc/login?redirect="+url.QueryEscape(redirect), nil, nil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		ts.t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	cookie := findCookie(resp, "yotei_oidc")
+	if cookie == nil || cookie.Value == "" || !cookie.HttpOnly {
+		ts.t.Fatalf("OIDC state cookie = %+v", cookie)
+	}
+	return resp.Header.Get(fiber.HeaderLocation), cookie
+}
+
+// oidcCallback はプロバイダからのリダイレクトとしてコールバックを呼ぶ
+func (ts *testServer) oidcCallback(code, state string, cookie *http.Cookie) *http.Response {
+	ts.t.Helper()
+
+	query := url.Values{"code": {code}, "state": {state}}
+	return ts.request(http.MethodGet, "/api/v1/auth/oidc/callback?"+query.Encode(), nil, map[string]string{
+		fiber.HeaderCookie: cookie.Name + "=" + cookie.Value,
+	})
+}
+
+// me はセッション Cookie のユーザーを返す
+func (ts *testServer) me(session *http.Cookie) models.User {
+	ts.t.Helper()
+
+	var user models.User
+	resp := ts.request(http.MethodGet, "/api/v1/me", nil, map[string]string{fiber.HeaderCookie: session.Name + "=" + session.Value})
+	decodeJSON(ts.t, resp, http.StatusOK, &user)
+	return user
+}
+
+func findCookie(resp *http.Response, name string) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func stateOf(t *testing.T, authURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization url: %v", err)
+	}
+	return u.Query().Get("state")
+}
+
+// loginWithOIDC はプロバイダでのログインからコールバックまでを通し、コールバックの応答を返す
+func (ts *testServer) loginWithOIDC(issuer *mockIssuer, claims map[string]any) *http.Response {
+	ts.t.Helper()
+
+	authURL, cookie := ts.oidcLogin("/events")
+	return ts.oidcCallback(issuer.authorize(authURL, claims), stateOf(ts.t, authURL), cookie)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	ts, issuer := newOIDCTestServer(t)
+
+	authURL, cookie := ts.oidcLogin("/events/abc?tab=summary")
+	claims := map[string]any{"sub": "user-1", "email": "Alice@Example.com", "email_verified": true, "name": "Alice"}
+	resp := ts.oidcCallback(issuer.authorize(authURL, claims), stateOf(t, authURL), cookie)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	if got := resp.Header.Get(fiber.HeaderLocation); got != "https://yotei.example.com/events/abc?tab=summary" {
+		t.Errorf("Location = %s", got)
+	}
+	if cleared := findCookie(resp, "yotei_oidc"); cleared == nil || cleared.Value != "" {
+		t.Errorf("OIDC state cookie was not cleared: %+v", cleared)
+	}
+
+	// PKCE の検証値は Cookie からトークンエンドポイントに渡る
+	verifiers := issuer.receivedVerifiers()
+	if len(verifiers) != 1 || verifiers[0] == "" {
+		t.Fatalf("code_verifier = %q", verifiers)
+	}
+	sum := sha256.Sum256([]byte(verifiers[0]))
+	if challenge, _ := url.Parse(authURL); challenge.Query().Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Error("code_challenge is not the S256 hash of code_verifier")
+	}
+
+	session := findCookie(resp, "yotei_session")
+	if session == nil || session.Value == "" {
+		t.Fatal("session cookie is not set")
+	}
+	user := ts.me(session)
+	if user.Email != "alice@example.com" || user.Name != "Alice" {
+		t.Errorf("user = %+v", user)
+	}
+
+	// 2回目以降はプロバイダのアカウントで同じユーザーを読み込む。メールアドレスが変わっていても同じ
+	resp = ts.loginWithOIDC(issuer, map[string]any{"sub": "user-1", "email": "alice@new.example.com", "email_verified": true})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("second login status = %d", resp.StatusCode)
+	}
+	if again := ts.me(findCookie(resp, "yotei_session")); again.ID != user.ID {
+		t.Errorf("second login user = %s, want %s", again.ID, user.ID)
+	}
+}
+
+func TestOIDCLoginLinksPasswordlessAccount(t *testing.T) {
+	ts, issuer := newOIDCTestServer(t)
+
+	resp := ts.loginWithOIDC(issuer, map[string]any{"sub": "user-1", "email": "alice@example.com", "email_verified": true})
+	resp.Body.Close()
+	existing := ts.me(findCookie(resp, "yotei_session"))
+
+	// 同じメールアドレスの別のプロバイダのアカウントは、パスワードのないユーザーに紐づける
+	resp = ts.loginWithOIDC(issuer, map[string]any{"sub": "user-2", "email": "ALICE@example.com", "email_verified": true})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	if user := ts.me(findCookie(resp, "yotei_session")); user.ID != existing.ID {
+		t.Errorf("user = %s, want the existing user %s", user.ID, existing.ID)
+	}
+}
+
+func TestOIDCLoginDoesNotLinkPasswordAccount(t *testing.T) {
+	ts, issuer := newOIDCTestServer(t)
+	// 本人より先に他人がパスワードで登録したアカウント
+	ts.signUp("alice@example.com", "Attacker")
+
+	resp := ts.loginWithOIDC(issuer, map[string]any{"sub": "user-1", "email": "ALICE@example.com", "email_verified": true})
+	if findCookie(resp, "yotei_session") != nil {
+		t.Error("session cookie is set for an account registered with a password")
+	}
+	expectError(t, resp, http.StatusConflict, handlers.CodeEmailTaken)
+}
+
+func TestOIDCLoginRejectsUnverifiedEmailOfAnotherAccount(t *testing.T) {
+	ts, issuer := newOIDCTestServer(t)
+	ts.signUp("alice@example.com", "Alice")
+
+	resp := ts.loginWithOIDC(issuer, map[string]any{"sub": "attacker", "email": "alice@example.com", "email_verified": false})
+	if findCookie(resp, "yotei_session") != nil {
+		t.Error("session cookie is set for an unverified email")
+	}
+	expectError(t, resp, http.StatusConflict, handlers.CodeEmailTaken)
+
+	// 他と重ならなければ未確認のメールアドレスでも登録できる
+	resp = ts.loginWithOIDC(issuer, map[string]any{"sub": "bob", "email": "bob@example.com", "email_verified": false})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	if user := ts.me(findCookie(resp, "yotei_session")); user.Email != "bob@example.com" || user.Name != "bob" {
+		t.Errorf("user = %+v", user)
+	}
+}
+
+func TestOIDCCallbackRejected(t *testing.T) {
+	claims := map[string]any{"sub": "user-1", "email": "alice@example.com", "email_verified": true}
+
+	tests := []struct {
+		name string
+		// callback はログインを開始した後、コールバックを呼ぶ
+		callback func(ts *testServer, issuer *mockIssuer) *http.Response
+		// トークンエンドポイントまで進むかどうか
+		exchanged bool
+	}{
+		{
+			name: "state mismatch",
+			callback: func(ts *testServer, issuer *mockIssuer) *http.Response {
+				authURL, cookie := ts.oidcLogin("/")
+				return ts.oidcCallback(issuer.authorize(authURL, claims), "forged-state", cookie)
+			},
+		},
+		{
+			name: "missing state cookie",
+			callback: func(ts *testServer, issuer *mockIssuer) *http.Response {
+				authURL, _ := ts.oidcLogin("/")
+				return ts.oidcCallback(issuer.authorize(authURL, claims), stateOf(ts.t, authURL), &http.Cookie{Name: "yotei_oidc"})
+			},
+		},
+		{
+			name: "nonce mismatch",
+			callback: func(ts *testServer, issuer *mockIssuer) *http.Response {
+				replayed := map[string]any{"nonce": "nonce-of-another-login"}
+				for name, value := range claims {
+					replayed[name] = value
+				}
+				return ts.loginWithOIDC(issuer, replayed)
+			},
+			exchanged: true,
+		},
+		{
+			// 別のログインの認可コードは、Cookie の検証値が code_challenge と一致しないのでプロバイダが拒否する
+			name: "code verifier of another login",
+			callback: func(ts *testServer, issuer *mockIssuer) *http.Response {
+				victimURL, _ := ts.oidcLogin("/")
+				attackerURL, attackerCookie := ts.oidcLogin("/")
+				return ts.oidcCallback(issuer.authorize(victimURL, claims), stateOf(ts.t, attackerURL), attackerCookie)
+			},
+			exchanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, issuer := newOIDCTestServer(t)
+
+			resp := tt.callback(ts, issuer)
+			if findCookie(resp, "yotei_session") != nil {
+				t.Error("session cookie is set for a rejected login")
+			}
+			expectError(t, resp, http.StatusUnauthorized, handlers.CodeOIDCFailed)
+			if exchanged := len(issuer.receivedVerifiers()) > 0; exchanged != tt.exchanged {
+				t.Errorf("token endpoint called = %v, want %v", exchanged, tt.exchanged)
+			}
+		})
+	}
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	for _, path := range []string{"/api/v1/auth/oidc/login", "/api/v1/auth/oidc/callback?code=x&state=y"} {
+		resp := ts.request(http.MethodGet, path, nil, nil)
+		if err := expectError(t, resp, http.StatusNotFound, handlers.CodeNotFound); !strings.Contains(err.Message, "OpenID Connect") {
+			t.Errorf("%s: message = %q", path, err.Message)
+		}
+	}
+}
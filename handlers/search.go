@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"yotei-backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultEventPageSize = 20
+	maxEventPageSize     = 100
+)
+
+type ListEventsRequest struct {
+	Q string `json:"q" query:"q" validate:"max=255"`
+	// ユーザーID。me でログイン中のユーザー
+	Creator        string `json:"creator" query:"creator" validate:"max=36"`
+	WorkspaceID    string `json:"workspace_id" query:"workspace_id" validate:"omitempty,uuid"`
+	Status         string `json:"status" query:"status" validate:"omitempty,oneof=open decided"`
+	CreatedAfter   string `json:"created_after" query:"created_after" validate:"omitempty,rfc3339"`
+	CreatedBefore  string `json:"created_before" query:"created_before" validate:"omitempty,rfc3339"`
+	DeadlineAfter  string `json:"deadline_after" query:"deadline_after" validate:"omitempty,rfc3339"`
+	DeadlineBefore string `json:"deadline_before" query:"deadline_before" validate:"omitempty,rfc3339"`
+	// 先頭に - を付けると降順。デフォルトは -created_at
+	Sort   string `json:"sort" query:"sort" validate:"omitempty,oneof=created_at -created_at deadline -deadline title -title"`
+	Limit  int    `json:"limit" query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `json:"cursor" query:"cursor"`
+}
+
+type ListEventsResponse struct {
+	Events []DashboardEvent `json:"events"`
+	// 次のページがない場合は空
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// pageCursor はクライアントに返すカーソル。並び順を変えて使い回せないよう並び順も含める
+type pageCursor struct {
+	Sort string `json:"sort"`
+	store.EventCursor
+}
+
+func encodeCursor(sort string, cursor *store.EventCursor) string {
+	encoded, _ := json.Marshal(pageCursor{Sort: sort, EventCursor: *cursor})
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(sort, value string) (*store.EventCursor, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, false
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.Sort != sort || cursor.ID == "" {
+		return nil, false
+	}
+	return &cursor.EventCursor, true
+}
+
+// ListEvents はログイン中のユーザーが作成したイベントと、所属するワークスペースのイベントを検索する
+func (h *Handler) ListEvents(c *fiber.Ctx) error {
+	var req ListEventsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+
+	user := currentUser(c)
+	q := store.EventQuery{
+		VisibleTo:   user.ID,
+		OwnerID:     req.Creator,
+		WorkspaceID: req.WorkspaceID,
+		Status:      req.Status,
+		Title:       strings.TrimSpace(req.Q),
+		Limit:       req.Limit,
+	}
+	if q.OwnerID == "me" {
+		q.OwnerID = user.ID
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultEventPageSize
+	}
+	if req.Sort == "" {
+		req.Sort = "-" + store.SortCreatedAt
+	}
+	q.Sort, q.Desc = strings.TrimPrefix(req.Sort, "-"), strings.HasPrefix(req.Sort, "-")
+
+	// 検証済みなのでエラーにはならない
+	for _, r := range []struct {
+		value string
+		dest  **time.Time
+	}{
+		{req.CreatedAfter, &q.CreatedAfter},
+		{req.CreatedBefore, &q.CreatedBefore},
+		{req.DeadlineAfter, &q.DeadlineAfter},
+		{req.DeadlineBefore, &q.DeadlineBefore},
+	} {
+		if r.value != "" {
+			t, _ := time.Parse(time.RFC3339, r.value)
+			*r.dest = &t
+		}
+	}
+
+	if req.Cursor != "" {
+		cursor, ok := decodeCursor(req.Sort, req.Cursor)
+		if !ok {
+			verr := &ValidationError{}
+			verr.Add("cursor", "is invalid or was issued for a different sort order")
+			return verr
+		}
+		q.After = cursor
+	}
+
+	if q.WorkspaceID != "" {
+		if _, err := h.workspaceMember(c, q.WorkspaceID); err != nil {
+			return err
+		}
+	}
+
+	// 次のページがあるかを知るため1件多く取得する
+	q.Limit++
+	events, err := h.store.ListEvents(c.UserContext(), q)
+	if err != nil {
+		return internalError("Failed to search events", err)
+	}
+
+	response := ListEventsResponse{}
+	if len(events) == q.Limit {
+		events = events[:len(events)-1]
+		response.NextCursor = encodeCursor(req.Sort, q.CursorFor(&events[len(events)-1]))
+	}
+	response.Events, err = h.dashboardEvents(c, events)
+	if err != nil {
+		return err
+	}
+	return c.JSON(response)
+}
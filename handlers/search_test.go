@@ -0,0 +1,67 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+)
+
+func TestListEventsPaging(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	token := ts.signUp("owner@example.com", "Owner")
+	other := ts.signUp("other@example.com", "Other")
+	date := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	for _, title := range []string{"Meeting C", "Meeting A", "Lunch", "Meeting B"} {
+		ts.createEvent(eventRequest(title, date), bearer(token))
+	}
+	ts.createEvent(eventRequest("Meeting D", date), bearer(other))
+
+	list := func(query url.Values) handlers.ListEventsResponse {
+		t.Helper()
+		var page handlers.ListEventsResponse
+		decodeJSON(t, ts.request(http.MethodGet, "/api/v1/events?"+query.Encode(), nil, bearer(token)), http.StatusOK, &page)
+		return page
+	}
+
+	// 他のユーザーのイベントは含めず、カーソルで最後まで辿れる
+	query := url.Values{"q": {"meeting"}, "sort": {"title"}, "limit": {"2"}}
+	var titles []string
+	for page := 0; ; page++ {
+		if page > 2 {
+			t.Fatal("paging did not end")
+		}
+		result := list(query)
+		for _, event := range result.Events {
+			titles = append(titles, event.Title)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", result.NextCursor)
+	}
+	want := []string{"Meeting A", "Meeting B", "Meeting C"}
+	if len(titles) != len(want) {
+		t.Fatalf("titles = %v, want %v", titles, want)
+	}
+	for i := range want {
+		if titles[i] != want[i] {
+			t.Fatalf("titles = %v, want %v", titles, want)
+		}
+	}
+
+	// 並び順を変えてカーソルを使い回すことはできない
+	first := list(url.Values{"sort": {"title"}, "limit": {"1"}})
+	if first.NextCursor == "" {
+		t.Fatal("first page has no next_cursor")
+	}
+	path := "/api/v1/events?" + url.Values{"sort": {"-title"}, "cursor": {first.NextCursor}}.Encode()
+	expectError(t, ts.request(http.MethodGet, path, nil, bearer(token)), http.StatusBadRequest, handlers.CodeValidationFailed)
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events?cursor=not-a-cursor", nil, bearer(token)), http.StatusBadRequest, handlers.CodeValidationFailed)
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events?limit=101", nil, bearer(token)), http.StatusBadRequest, handlers.CodeValidationFailed)
+	workspaceID := ts.createWorkspace(other, "Team")
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events?workspace_id="+workspaceID, nil, bearer(token)), http.StatusNotFound, handlers.CodeWorkspaceNotFound)
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events", nil, nil), http.StatusUnauthorized, handlers.CodeUnauthorized)
+}
@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"yotei-backend/metrics"
+	"yotei-backend/models"
+	"yotei-backend/store"
+	"yotei-backend/telemetry"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// シリーズがイベントを作成する最短の間隔。誤った cron 式で大量のイベントが作られないようにする
+const minSeriesInterval = 24 * time.Hour
+
+type CandidateRuleRequest struct {
+	Period   string   `json:"period" validate:"required,oneof=next_week next_month"`
+	Weeks    []int    `json:"weeks" validate:"max=5,unique,dive,min=1,max=5"`
+	Weekdays []int    `json:"weekdays" validate:"required,min=1,max=7,unique,dive,min=0,max=6"`
+	Times    []string `json:"times" validate:"required,min=1,max=3,unique,dive,clock"`
+}
+
+type SeriesSettingsRequest struct {
+	AllowSettingChanges   bool `json:"allow_setting_changes"`
+	DeadlineEnable        bool `json:"deadline_enable"`
+	DeadlineDaysBefore    int  `json:"deadline_days_before" validate:"min=0,max=365"`
+	AutoDecisionEnable    bool `json:"auto_decision_enable"`
+	AutoDecisionThreshold int  `json:"auto_decision_threshold"`
+	RSSEnabled            bool `json:"rss_enabled"`
+}
+
+type SeriesRequest struct {
+	Title       string `json:"title" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=10000"`
+	// 作成時のみ指定できる。オーナーかオーガナイザーのみ
+	WorkspaceID string `json:"workspace_id" validate:"omitempty,uuid"`
+	// cron 式（分 時 日 月 曜日）。例: "0 9 1 * *" で毎月1日の9時
+	Schedule      string                `json:"schedule" validate:"required,max=100"`
+	CandidateRule CandidateRuleRequest  `json:"candidate_rule"`
+	Settings      SeriesSettingsRequest `json:"settings"`
+	// 省略した場合は有効
+	Enabled *bool `json:"enabled"`
+}
+
+// parseSeriesSchedule は cron 式を読み、イベントの作成間隔が短すぎないことを確認する
+func (h *Handler) parseSeriesSchedule(spec string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		verr := &ValidationError{}
+		verr.Add("schedule", `must be a cron expression such as "0 9 1 * *"`)
+		return nil, verr
+	}
+	// 曜日の指定などで間隔が一定でない場合もあるので、直近の数回分で確認する
+	previous := schedule.Next(time.Now().In(h.location()))
+	for range 10 {
+		next := schedule.Next(previous)
+		if next.Sub(previous) < minSeriesInterval {
+			verr := &ValidationError{}
+			verr.Add("schedule", "must not run more than once a day")
+			return nil, verr
+		}
+		previous = next
+	}
+	return schedule, nil
+}
+
+func (req SeriesRequest) apply(series *models.EventSeries) {
+	series.Title = req.Title
+	series.Description = req.Description
+	series.Schedule = req.Schedule
+	series.CandidateRule = models.CandidateRule{
+		Period:   req.CandidateRule.Period,
+		Weeks:    req.CandidateRule.Weeks,
+		Weekdays: req.CandidateRule.Weekdays,
+		Times:    req.CandidateRule.Times,
+	}
+	series.Enabled = req.Enabled == nil || *req.Enabled
+	series.AllowSettingChanges = req.Settings.AllowSettingChanges
+	series.DeadlineEnable = req.Settings.DeadlineEnable
+	series.DeadlineDaysBefore = req.Settings.DeadlineDaysBefore
+	series.AutoDecisionEnable = req.Settings.AutoDecisionEnable
+	series.AutoDecisionThreshold = req.Settings.AutoDecisionThreshold
+	series.RSSEnabled = req.Settings.RSSEnabled
+}
+
+// seriesForUser はシリーズを返す。作成者かワークスペースのメンバー以外には存在を明かさない。
+// manage が true の場合は作成者かワークスペースのオーナー・オーガナイザーに限る
+func (h *Handler) seriesForUser(c *fiber.Ctx, manage bool) (*models.EventSeries, error) {
+	ctx := c.UserContext()
+	series, err := h.store.GetSeries(ctx, c.Params("seriesID"))
+	if err != nil {
+		return nil, lookupError(err, ErrSeriesNotFound, "Failed to get event series")
+	}
+
+	user := currentUser(c)
+	if series.OwnerID != nil && *series.OwnerID == user.ID {
+		return series, nil
+	}
+	if series.WorkspaceID == nil {
+		return nil, ErrSeriesNotFound
+	}
+	member, err := h.store.GetWorkspaceMember(ctx, *series.WorkspaceID, user.ID)
+	if err != nil {
+		return nil, lookupError(err, ErrSeriesNotFound, "Failed to get workspace member")
+	}
+	if manage && !member.CanManageEvents() {
+		return nil, ErrWorkspaceForbidden
+	}
+	return series, nil
+}
+
+// CreateSeries は定期的にイベントを作成するシリーズを作成する
+func (h *Handler) CreateSeries(c *fiber.Ctx) error {
+	var req SeriesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	schedule, err := h.parseSeriesSchedule(req.Schedule)
+	if err != nil {
+		return err
+	}
+	if err := h.validateCandidateDateCount("candidate_rule", maxSeriesCandidateDates(req.CandidateRule)); err != nil {
+		return err
+	}
+
+	user := currentUser(c)
+	series := models.EventSeries{
+		ID:          uuid.New().String(),
+		CreatorName: user.Name,
+		OwnerID:     &user.ID,
+		NextRunAt:   schedule.Next(time.Now().In(h.location())).UTC(),
+	}
+	if req.WorkspaceID != "" {
+		if _, err := h.workspaceMember(c, req.WorkspaceID, models.WorkspaceRoleOwner, models.WorkspaceRoleOrganizer); err != nil {
+			return err
+		}
+		series.WorkspaceID = &req.WorkspaceID
+	}
+	req.apply(&series)
+
+	if err := h.store.CreateSeries(c.UserContext(), &series); err != nil {
+		return internalError("Failed to create event series", err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(series)
+}
+
+// ListMySeries はログイン中のユーザーが作成したシリーズと、所属するワークスペースのシリーズを返す
+func (h *Handler) ListMySeries(c *fiber.Ctx) error {
+	series, err := h.store.ListSeries(c.UserContext(), currentUser(c).ID)
+	if err != nil {
+		return internalError("Failed to get event series", err)
+	}
+	return c.JSON(series)
+}
+
+func (h *Handler) GetSeries(c *fiber.Ctx) error {
+	series, err := h.seriesForUser(c, false)
+	if err != nil {
+		return err
+	}
+	return c.JSON(series)
+}
+
+// UpdateSeries はシリーズを変更する。作成済みのイベントは変更しない
+func (h *Handler) UpdateSeries(c *fiber.Ctx) error {
+	var req SeriesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	schedule, err := h.parseSeriesSchedule(req.Schedule)
+	if err != nil {
+		return err
+	}
+	if err := h.validateCandidateDateCount("candidate_rule", maxSeriesCandidateDates(req.CandidateRule)); err != nil {
+		return err
+	}
+	series, err := h.seriesForUser(c, true)
+	if err != nil {
+		return err
+	}
+
+	// 周期を変えた場合と一時停止から再開した場合は、今から次の作成日時を計算し直す
+	reschedule := req.Schedule != series.Schedule || (!series.Enabled && (req.Enabled == nil || *req.Enabled))
+	req.apply(series)
+	if reschedule {
+		series.NextRunAt = schedule.Next(time.Now().In(h.location())).UTC()
+	}
+
+	if err := h.store.UpdateSeries(c.UserContext(), series, reschedule); err != nil {
+		return lookupError(err, ErrSeriesNotFound, "Failed to update event series")
+	}
+	return c.JSON(series)
+}
+
+// DeleteSeries はシリーズを削除する。作成済みのイベントは残る
+func (h *Handler) DeleteSeries(c *fiber.Ctx) error {
+	series, err := h.seriesForUser(c, true)
+	if err != nil {
+		return err
+	}
+	if err := h.store.DeleteSeries(c.UserContext(), series.ID); err != nil {
+		return lookupError(err, ErrSeriesNotFound, "Failed to delete event series")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListSeriesEvents はシリーズから作成したイベントを新しい順に返す
+func (h *Handler) ListSeriesEvents(c *fiber.Ctx) error {
+	series, err := h.seriesForUser(c, false)
+	if err != nil {
+		return err
+	}
+	events, err := h.store.ListEventsBySeries(c.UserContext(), series.ID)
+	if err != nil {
+		return internalError("Failed to get events", err)
+	}
+	return h.sendDashboardEvents(c, events)
+}
+
+// RunScheduledJobs はスケジューラで定期的に実行する処理。1つが失敗しても残りの処理は行う
+func (h *Handler) RunScheduledJobs(ctx context.Context) error {
+	return errors.Join(h.GenerateDueSeries(ctx), h.CheckDeadlinesAndFinalize(ctx), h.DeleteExpiredSessions(ctx))
+}
+
+// GenerateDueSeries は作成日時を過ぎたシリーズのイベントを作成する
+func (h *Handler) GenerateDueSeries(ctx context.Context) error {
+	now := time.Now()
+	due, err := h.store.ListDueSeries(ctx, now)
+	if err != nil {
+		return fmt.Errorf("Failed to get event series: %w", err)
+	}
+	slog.DebugContext(ctx, "Checking event series", "series", len(due))
+
+	var errs []error
+	for _, series := range due {
+		// 停止処理中はキャンセルされるので、次のシリーズに進まずに終了する
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := h.generateSeriesEvent(ctx, &series, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *Handler) generateSeriesEvent(ctx context.Context, series *models.EventSeries, now time.Time) (err error) {
+	ctx, span := telemetry.Start(ctx, "generate series event", attribute.String("series.id", series.ID))
+	defer func() { telemetry.End(span, err) }()
+
+	schedule, err := cron.ParseStandard(series.Schedule)
+	if err != nil {
+		return fmt.Errorf("Invalid schedule for event series %s: %w", series.ID, err)
+	}
+	now = now.In(h.location())
+	// 停止していた間の分はまとめて1回だけ作成する
+	nextRunAt := schedule.Next(now)
+
+	dates := seriesCandidateDates(series.CandidateRule, now)
+	if limit := h.config.MaxCandidateDatesPerEvent; limit > 0 && len(dates) > limit {
+		// シリーズの作成後に上限を下げた場合は、古い順に上限まで使う
+		slog.WarnContext(ctx, "Too many candidate dates for event series; using the earliest ones",
+			"series_id", series.ID, "count", len(dates), "limit", limit)
+		dates = dates[:limit]
+	}
+	if len(dates) == 0 {
+		// 第5週のように期間内に該当する日がない場合は作成せずに次回に進める
+		slog.WarnContext(ctx, "No candidate dates for event series", "series_id", series.ID)
+		err = h.store.CreateSeriesEvent(ctx, series, nil, nextRunAt)
+	} else {
+		event := h.seriesEvent(ctx, series, dates, now)
+		err = h.store.CreateSeriesEvent(ctx, series, event, nextRunAt)
+		if err == nil {
+			metrics.EventsCreated.Inc()
+			slog.InfoContext(ctx, "Event created from series", "series_id", series.ID, "event_id", event.ID)
+		}
+	}
+	if errors.Is(err, store.ErrConflict) {
+		// 別のレプリカが先に作成した
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to create event for series %s: %w", series.ID, err)
+	}
+	return nil
+}
+
+func (h *Handler) seriesEvent(ctx context.Context, series *models.EventSeries, dates []time.Time, now time.Time) *models.Event {
+	event := &models.Event{
+		ID:                    uuid.New().String(),
+		Title:                 fmt.Sprintf("%s（%s）", series.Title, seriesPeriodLabel(series.CandidateRule.Period, dates[0])),
+		Description:           series.Description,
+		CreatorName:           series.CreatorName,
+		OwnerID:               series.OwnerID,
+		WorkspaceID:           series.WorkspaceID,
+		SeriesID:              &series.ID,
+		AllowSettingChanges:   series.AllowSettingChanges,
+		AutoDecisionEnable:    series.AutoDecisionEnable,
+		AutoDecisionThreshold: series.AutoDecisionThreshold,
+		RSSEnabled:            series.RSSEnabled,
+	}
+	for _, date := range dates {
+		event.CandidateDates = append(event.CandidateDates, models.CandidateDate{EventID: event.ID, DateTime: date})
+	}
+	if series.DeadlineEnable {
+		deadline := dates[0].AddDate(0, 0, -series.DeadlineDaysBefore)
+		if deadline.After(now) {
+			event.DeadlineEnable = true
+			event.Deadline = &deadline
+		} else {
+			slog.WarnContext(ctx, "Deadline of event series is already past; creating the event without a deadline",
+				"series_id", series.ID, "deadline", deadline)
+		}
+	}
+	return event
+}
+
+// maxSeriesCandidateDates は規則から作成されうる候補日の数の最大値
+func maxSeriesCandidateDates(rule CandidateRuleRequest) int {
+	if rule.Period == models.SeriesPeriodNextWeek {
+		return len(rule.Weekdays) * len(rule.Times)
+	}
+	weeks := rule.Weeks
+	if len(weeks) == 0 {
+		weeks = []int{1, 2, 3, 4, 5}
+	}
+	days := 0
+	for _, week := range weeks {
+		// 第5週は29日から31日までの最大3日
+		length := 7
+		if week == 5 {
+			length = 3
+		}
+		days += min(len(rule.Weekdays), length)
+	}
+	return days * len(rule.Times)
+}
+
+// seriesCandidateDates は now の翌週または翌月のうち、規則に合う日時を古い順に返す
+func seriesCandidateDates(rule models.CandidateRule, now time.Time) []time.Time {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	var start, end time.Time
+	switch rule.Period {
+	case models.SeriesPeriodNextWeek:
+		// 月曜始まり
+		start = today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+		end = start.AddDate(0, 0, 7)
+	case models.SeriesPeriodNextMonth:
+		start = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 1, 0)
+	default:
+		return nil
+	}
+
+	var clocks []time.Time
+	for _, value := range rule.Times {
+		if clock, err := time.Parse("15:04", value); err == nil {
+			clocks = append(clocks, clock)
+		}
+	}
+	slices.SortFunc(clocks, time.Time.Compare)
+
+	var dates []time.Time
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !slices.Contains(rule.Weekdays, int(day.Weekday())) {
+			continue
+		}
+		// 週の指定は翌月の場合だけ使う
+		if rule.Period == models.SeriesPeriodNextMonth && len(rule.Weeks) > 0 && !slices.Contains(rule.Weeks, (day.Day()-1)/7+1) {
+			continue
+		}
+		for _, clock := range clocks {
+			dates = append(dates, time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc))
+		}
+	}
+	return dates
+}
+
+// seriesPeriodLabel は作成したイベントのタイトルに付ける期間の表示
+func seriesPeriodLabel(period string, first time.Time) string {
+	if period == models.SeriesPeriodNextWeek {
+		// 最初の候補日の週の月曜日
+		monday := first.AddDate(0, 0, -(int(first.Weekday())+6)%7)
+		return monday.Format("2006年1月2日") + "の週"
+	}
+	return first.Format("2006年1月")
+}
@@ -0,0 +1,155 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+	"yotei-backend/models"
+)
+
+func seriesRequest(schedule string, weekdays []int, times ...string) map[string]any {
+	return map[string]any{
+		"title":    "Weekly sync",
+		"schedule": schedule,
+		"candidate_rule": map[string]any{
+			"period":   "next_week",
+			"weekdays": weekdays,
+			"times":    times,
+		},
+	}
+}
+
+func TestCreateSeriesValidation(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxCandidateDatesPerEvent = 10
+	ts := newTestServer(t, cfg)
+	token := ts.signUp("owner@example.com", "Owner")
+
+	var series models.EventSeries
+	decodeJSON(t, ts.request(http.MethodPost, "/api/v1/series", seriesRequest("0 9 * * 5", []int{1, 3}, "10:00"), bearer(token)), http.StatusCreated, &series)
+	if !series.Enabled || series.NextRunAt.Weekday() != time.Friday || !series.NextRunAt.After(time.Now()) {
+		t.Fatalf("series = %+v, want enabled and next run on a Friday", series)
+	}
+
+	tests := []struct {
+		name  string
+		req   map[string]any
+		field string
+	}{
+		{"invalid cron expression", seriesRequest("every friday", []int{1}, "10:00"), "schedule"},
+		{"more than once a day", seriesRequest("*/30 * * * *", []int{1}, "10:00"), "schedule"},
+		{"twice a day", seriesRequest("0 9,18 * * *", []int{1}, "10:00"), "schedule"},
+		// 7 曜日 × 3 時刻 = 21 件が上限の 10 件を超える
+		{"too many candidate dates", seriesRequest("0 9 * * 5", []int{0, 1, 2, 3, 4, 5, 6}, "09:00", "12:00", "18:00"), "candidate_rule"},
+		{"invalid time", seriesRequest("0 9 * * 5", []int{1}, "25:00"), "candidate_rule.times[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := ts.request(http.MethodPost, "/api/v1/series", tt.req, bearer(token))
+			apiErr := expectError(t, resp, http.StatusBadRequest, handlers.CodeValidationFailed)
+			if !slices.ContainsFunc(apiErr.Details, func(fe handlers.FieldError) bool { return fe.Field == tt.field }) {
+				t.Errorf("details = %+v, want an error for %s", apiErr.Details, tt.field)
+			}
+		})
+	}
+
+	expectError(t, ts.request(http.MethodPost, "/api/v1/series", seriesRequest("0 9 * * 5", []int{1}, "10:00"), nil), http.StatusUnauthorized, handlers.CodeUnauthorized)
+}
+
+func TestSeriesPermissions(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	owner := ts.signUp("owner@example.com", "Owner")
+	member := ts.signUp("member@example.com", "Member")
+	outsider := ts.signUp("outsider@example.com", "Outsider")
+	workspaceID := ts.createWorkspace(owner, "Team")
+	ts.addMember(owner, workspaceID, "member@example.com", "member")
+
+	req := seriesRequest("0 9 * * 5", []int{1}, "10:00")
+	req["workspace_id"] = workspaceID
+	expectError(t, ts.request(http.MethodPost, "/api/v1/series", req, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+	var series models.EventSeries
+	decodeJSON(t, ts.request(http.MethodPost, "/api/v1/series", req, bearer(owner)), http.StatusCreated, &series)
+	path := "/api/v1/series/" + series.ID
+
+	// メンバーは参照でき、変更と削除はオーナー・オーガナイザーだけ
+	expectError(t, ts.request(http.MethodGet, path, nil, bearer(outsider)), http.StatusNotFound, handlers.CodeSeriesNotFound)
+	decodeJSON(t, ts.request(http.MethodGet, path, nil, bearer(member)), http.StatusOK, nil)
+	expectError(t, ts.request(http.MethodPut, path, req, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+	expectError(t, ts.request(http.MethodDelete, path, nil, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+
+	// 周期を変えると次の作成日時を計算し直す
+	req["schedule"] = "0 9 * * 1"
+	var updated models.EventSeries
+	decodeJSON(t, ts.request(http.MethodPut, path, req, bearer(owner)), http.StatusOK, &updated)
+	if updated.NextRunAt.Weekday() != time.Monday {
+		t.Errorf("next_run_at = %s, want a Monday", updated.NextRunAt)
+	}
+	decodeJSON(t, ts.request(http.MethodDelete, path, nil, bearer(owner)), http.StatusNoContent, nil)
+	expectError(t, ts.request(http.MethodGet, path, nil, bearer(owner)), http.StatusNotFound, handlers.CodeSeriesNotFound)
+}
+
+func TestGenerateDueSeries(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	ctx := context.Background()
+	token := ts.signUp("owner@example.com", "Owner")
+
+	var created models.EventSeries
+	decodeJSON(t, ts.request(http.MethodPost, "/api/v1/series", seriesRequest("0 9 * * 5", []int{1, 3}, "15:00", "10:00"), bearer(token)), http.StatusCreated, &created)
+
+	// 作成日時を過ぎた状態にする
+	series, err := ts.store.GetSeries(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series.NextRunAt = time.Now().Add(-time.Minute)
+	if err := ts.store.UpdateSeries(ctx, series, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ts.handler.GenerateDueSeries(ctx); err != nil {
+		t.Fatalf("GenerateDueSeries: %v", err)
+	}
+	// 次の作成日時に進んでいるので、もう一度実行しても作成しない
+	if err := ts.handler.GenerateDueSeries(ctx); err != nil {
+		t.Fatalf("GenerateDueSeries: %v", err)
+	}
+
+	var events []handlers.DashboardEvent
+	decodeJSON(t, ts.request(http.MethodGet, "/api/v1/series/"+created.ID+"/events", nil, bearer(token)), http.StatusOK, &events)
+	if len(events) != 1 || !strings.HasPrefix(events[0].Title, "Weekly sync（") {
+		t.Fatalf("series events = %+v, want one event", events)
+	}
+
+	// 翌週の月曜と水曜の 10:00 と 15:00
+	event := ts.getEvent(events[0].ID, bearer(token))
+	ids := candidateDateIDs(t, event)
+	if len(ids) != 4 {
+		t.Fatalf("candidate dates = %+v, want 4", event.CandidateDates)
+	}
+	dates := map[uint]time.Time{}
+	for _, candidateDate := range event.CandidateDates {
+		dates[candidateDate.ID] = candidateDate.DateTime.UTC()
+	}
+	want := []struct {
+		weekday time.Weekday
+		hour    int
+	}{{time.Monday, 10}, {time.Monday, 15}, {time.Wednesday, 10}, {time.Wednesday, 15}}
+	for i, id := range ids {
+		if date := dates[id]; date.Weekday() != want[i].weekday || date.Hour() != want[i].hour || !date.After(time.Now()) {
+			t.Errorf("candidate_dates[%d] = %s, want a future %s at %d:00", i, date, want[i].weekday, want[i].hour)
+		}
+	}
+
+	updated, err := ts.store.GetSeries(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.NextRunAt.After(time.Now()) || updated.LastRunAt == nil {
+		t.Errorf("series = next %s, last %v, want a future next run", updated.NextRunAt, updated.LastRunAt)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"slices"
+	"time"
+
+	"yotei-backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CandidateDateSummary struct {
+	ID          uint      `json:"id"`
+	DateTime    time.Time `json:"date_time"`
+	Available   int64     `json:"available"`
+	Maybe       int64     `json:"maybe"`
+	Unavailable int64     `json:"unavailable"`
+	// 参加者のうちこの候補日に回答していない人数
+	NoAnswer int64 `json:"no_answer"`
+	Leading  bool  `json:"leading"`
+}
+
+// EventSummary は回答を読み込まずに集計したイベントの概要
+type EventSummary struct {
+	ID               string `json:"id"`
+	Title            string `json:"title"`
+	Status           string `json:"status"`
+	ParticipantCount int64  `json:"participant_count"`
+
+	DeadlineEnable        bool       `json:"deadline_enable"`
+	Deadline              *time.Time `json:"deadline"`
+	DeadlineReached       bool       `json:"deadline_reached"`
+	AutoDecisionEnable    bool       `json:"auto_decision_enable"`
+	AutoDecisionThreshold int        `json:"auto_decision_threshold"`
+	AutoDecisionReached   bool       `json:"auto_decision_reached"`
+
+	CandidateDates []CandidateDateSummary `json:"candidate_dates"`
+	// 日程決定と同じ基準で選んだ、参加可能が最も多い候補日
+	LeadingCandidateDateIDs []uint `json:"leading_candidate_date_ids"`
+}
+
+// GetEventSummary は候補日ごとの回答数と現在の最有力候補を返す
+func (h *Handler) GetEventSummary(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	event, err := h.store.GetEvent(ctx, c.Params("id"))
+	if err != nil {
+		return eventLookupError(err)
+	}
+	if err := h.authorizeEvent(c, event); err != nil {
+		return err
+	}
+
+	tallies, err := h.store.TallyResponses(ctx, event.ID)
+	if err != nil {
+		return internalError("Failed to count responses", err)
+	}
+	participantCount, err := h.store.CountParticipants(ctx, event.ID)
+	if err != nil {
+		return internalError("Failed to count participants", err)
+	}
+
+	leaders := []uint{}
+	for _, tally := range leadingCandidates(tallies) {
+		leaders = append(leaders, tally.CandidateDateID)
+	}
+
+	summary := EventSummary{
+		ID:                      event.ID,
+		Title:                   event.Title,
+		Status:                  eventStatus(event),
+		ParticipantCount:        participantCount,
+		DeadlineEnable:          event.DeadlineEnable,
+		Deadline:                event.Deadline,
+		DeadlineReached:         event.DeadlineReached,
+		AutoDecisionEnable:      event.AutoDecisionEnable,
+		AutoDecisionThreshold:   event.AutoDecisionThreshold,
+		AutoDecisionReached:     event.AutoDecisionReached,
+		CandidateDates:          make([]CandidateDateSummary, len(tallies)),
+		LeadingCandidateDateIDs: leaders,
+	}
+	for i, tally := range tallies {
+		summary.CandidateDates[i] = candidateDateSummary(tally, participantCount, slices.Contains(leaders, tally.CandidateDateID))
+	}
+	return c.JSON(summary)
+}
+
+func candidateDateSummary(tally store.CandidateDateTally, participantCount int64, leading bool) CandidateDateSummary {
+	return CandidateDateSummary{
+		ID:          tally.CandidateDateID,
+		DateTime:    tally.DateTime,
+		Available:   tally.Available,
+		Maybe:       tally.Maybe,
+		Unavailable: tally.Unavailable,
+		NoAnswer:    max(participantCount-tally.Available-tally.Maybe-tally.Unavailable, 0),
+		Leading:     leading,
+	}
+}
@@ -0,0 +1,56 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+)
+
+func TestGetEventSummary(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+
+	first := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	eventID := ts.createEvent(eventRequest("Team lunch", first, first.AddDate(0, 0, 1), first.AddDate(0, 0, 2)), nil)
+	ids := candidateDateIDs(t, ts.getEvent(eventID, nil))
+	for _, v := range []map[string]any{
+		vote(eventID, 1, "Alice", ids[:2], ids[2:]),
+		vote(eventID, 2, "Bob", ids[1:2], ids[:1]),
+		vote(eventID, 3, "Carol", ids[1:2], nil),
+	} {
+		decodeJSON(t, ts.request(http.MethodPost, participantPath(eventID), v, nil), http.StatusCreated, nil)
+	}
+
+	var summary handlers.EventSummary
+	decodeJSON(t, ts.request(http.MethodGet, "/api/v1/events/"+eventID+"/summary", nil, nil), http.StatusOK, &summary)
+	if summary.ParticipantCount != 3 || summary.Status != "open" || len(summary.CandidateDates) != 3 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	want := []handlers.CandidateDateSummary{
+		{ID: ids[0], Available: 1, Unavailable: 1, NoAnswer: 1},
+		{ID: ids[1], Available: 3, Leading: true},
+		{ID: ids[2], Unavailable: 1, NoAnswer: 2},
+	}
+	for i, got := range summary.CandidateDates {
+		got.DateTime = time.Time{}
+		if got != want[i] {
+			t.Errorf("candidate_dates[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if len(summary.LeadingCandidateDateIDs) != 1 || summary.LeadingCandidateDateIDs[0] != ids[1] {
+		t.Errorf("leading_candidate_date_ids = %v, want [%d]", summary.LeadingCandidateDateIDs, ids[1])
+	}
+
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events/00000000-0000-0000-0000-000000000000/summary", nil, nil), http.StatusNotFound, handlers.CodeEventNotFound)
+
+	// ワークスペースのイベントはメンバー以外には見せない
+	owner := ts.signUp("owner@example.com", "Owner")
+	outsider := ts.signUp("outsider@example.com", "Outsider")
+	req := eventRequest("Workspace event", first)
+	req["workspace_id"] = ts.createWorkspace(owner, "Team")
+	workspaceEventID := ts.createEvent(req, bearer(owner))
+	path := "/api/v1/events/" + workspaceEventID + "/summary"
+	expectError(t, ts.request(http.MethodGet, path, nil, bearer(outsider)), http.StatusNotFound, handlers.CodeEventNotFound)
+	decodeJSON(t, ts.request(http.MethodGet, path, nil, bearer(owner)), http.StatusOK, nil)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"yotei-backend/models"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+" "+f.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("rfc3339", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(time.RFC3339, fl.Field().String())
+		return err == nil
+	})
+	// "19:00" のような時刻
+	v.RegisterValidation("clock", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse("15:04", fl.Field().String())
+		return err == nil
+	})
+	v.RegisterStructValidation(validateEventSettings, EventSettingsRequest{})
+	v.RegisterStructValidation(validateSeriesSettings, SeriesSettingsRequest{})
+	v.RegisterStructValidation(validateCandidateRule, CandidateRuleRequest{})
+	return v
+}
+
+func validateEventSettings(sl validator.StructLevel) {
+	req := sl.Current().Interface().(EventSettingsRequest)
+
+	if req.AutoDecisionEnable && req.AutoDecisionThreshold < 1 {
+		sl.ReportError(req.AutoDecisionThreshold, "auto_decision_threshold", "AutoDecisionThreshold", "min", "1")
+	}
+
+	if req.DeadlineEnable {
+		if req.Deadline == "" {
+			sl.ReportError(req.Deadline, "deadline", "Deadline", "required", "")
+		} else if _, err := time.Parse(time.RFC3339, req.Deadline); err != nil {
+			sl.ReportError(req.Deadline, "deadline", "Deadline", "rfc3339", "")
+		}
+	}
+}
+
+func validateSeriesSettings(sl validator.StructLevel) {
+	req := sl.Current().Interface().(SeriesSettingsRequest)
+
+	if req.AutoDecisionEnable && req.AutoDecisionThreshold < 1 {
+		sl.ReportError(req.AutoDecisionThreshold, "auto_decision_threshold", "AutoDecisionThreshold", "min", "1")
+	}
+}
+
+func validateCandidateRule(sl validator.StructLevel) {
+	req := sl.Current().Interface().(CandidateRuleRequest)
+
+	// 週の指定は月単位の期間でだけ使える
+	if req.Period != models.SeriesPeriodNextMonth && len(req.Weeks) > 0 {
+		sl.ReportError(req.Weeks, "weeks", "Weeks", "excluded_unless", "period next_month")
+	}
+}
+
+func validateStruct(req any) error {
+	err := validate.Struct(req)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	result := &ValidationError{}
+	for _, fe := range validationErrors {
+		result.Add(fieldPath(fe.Namespace()), fieldMessage(fe))
+	}
+	return result
+}
+
+// "CreateEventRequest.settings.deadline" -> "settings.deadline"
+func fieldPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s item(s)", fe.Param())
+		}
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s item(s)", fe.Param())
+		}
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "rfc3339":
+		return "must be in ISO 8601 format"
+	case "unique":
+		return "must not contain duplicates"
+	case "email":
+		return "must be a valid email address"
+	case "uuid":
+		return "must be a UUID"
+	case "clock":
+		return "must be a time in HH:MM format"
+	case "excluded_unless":
+		return fmt.Sprintf("can only be set when %s", strings.Replace(fe.Param(), " ", " is ", 1))
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	default:
+		return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+	}
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"yotei-backend/models"
+	"yotei-backend/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type WorkspaceRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type AddWorkspaceMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner organizer member"`
+}
+
+type UpdateWorkspaceMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner organizer member"`
+}
+
+// WorkspaceSummary はワークスペースとログイン中のユーザーの役割
+type WorkspaceSummary struct {
+	models.Workspace
+	Role string `json:"role"`
+}
+
+type WorkspaceDetails struct {
+	WorkspaceSummary
+	Members []models.WorkspaceMember `json:"members"`
+}
+
+// workspaceMember はログイン中のユーザーのワークスペースでの役割を返す。
+// roles を指定した場合はそのいずれかでなければ 403 を返す
+func (h *Handler) workspaceMember(c *fiber.Ctx, workspaceID string, roles ...string) (*models.WorkspaceMember, error) {
+	member, err := h.store.GetWorkspaceMember(c.UserContext(), workspaceID, currentUser(c).ID)
+	if err != nil {
+		return nil, lookupError(err, ErrWorkspaceNotFound, "Failed to get workspace member")
+	}
+	if len(roles) > 0 && !slices.Contains(roles, member.Role) {
+		return nil, ErrWorkspaceForbidden
+	}
+	return member, nil
+}
+
+// authorizeEvent はワークスペースのイベントをメンバー以外に見せない。ワークスペースに属さないイベントは誰でも参照できる。
+// 未ログインの場合も存在しないイベントと同じ 404 を返し、ID が存在することを明かさない
+func (h *Handler) authorizeEvent(c *fiber.Ctx, event *models.Event) error {
+	if event.WorkspaceID == nil {
+		return nil
+	}
+	user := currentUser(c)
+	if user == nil {
+		return ErrEventNotFound
+	}
+	if _, err := h.store.GetWorkspaceMember(c.UserContext(), *event.WorkspaceID, user.ID); err != nil {
+		return lookupError(err, ErrEventNotFound, "Failed to get workspace member")
+	}
+	return nil
+}
+
+// canManageEvent はイベントの作成者か、イベントが属するワークスペースのオーナー・オーガナイザーかどうか
+func (h *Handler) canManageEvent(c *fiber.Ctx, event *models.Event) (bool, error) {
+	if isOwner(c, event) {
+		return true, nil
+	}
+	user := currentUser(c)
+	if user == nil || event.WorkspaceID == nil {
+		return false, nil
+	}
+	member, err := h.store.GetWorkspaceMember(c.UserContext(), *event.WorkspaceID, user.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, internalError("Failed to get workspace member", err)
+	}
+	return member.CanManageEvents(), nil
+}
+
+// CreateWorkspace はワークスペースを作成し、作成したユーザーをオーナーにする
+func (h *Handler) CreateWorkspace(c *fiber.Ctx) error {
+	var req WorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+
+	workspace := models.Workspace{ID: uuid.New().String(), Name: req.Name}
+	if err := h.store.CreateWorkspace(c.UserContext(), &workspace, currentUser(c).ID); err != nil {
+		return internalError("Failed to create workspace", err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(WorkspaceSummary{Workspace: workspace, Role: models.WorkspaceRoleOwner})
+}
+
+// ListMyWorkspaces はログイン中のユーザーが所属するワークスペースを返す
+func (h *Handler) ListMyWorkspaces(c *fiber.Ctx) error {
+	memberships, err := h.store.ListWorkspacesByUser(c.UserContext(), currentUser(c).ID)
+	if err != nil {
+		return internalError("Failed to get workspaces", err)
+	}
+
+	response := make([]WorkspaceSummary, 0, len(memberships))
+	for _, membership := range memberships {
+		if membership.Workspace != nil {
+			response = append(response, WorkspaceSummary{Workspace: *membership.Workspace, Role: membership.Role})
+		}
+	}
+	return c.JSON(response)
+}
+
+func (h *Handler) GetWorkspace(c *fiber.Ctx) error {
+	workspaceID := c.Params("workspaceID")
+	member, err := h.workspaceMember(c, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.UserContext()
+	workspace, err := h.store.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return lookupError(err, ErrWorkspaceNotFound, "Failed to get workspace")
+	}
+	members, err := h.store.ListWorkspaceMembers(ctx, workspaceID)
+	if err != nil {
+		return internalError("Failed to get workspace members", err)
+	}
+	return c.JSON(WorkspaceDetails{
+		WorkspaceSummary: WorkspaceSummary{Workspace: *workspace, Role: member.Role},
+		Members:          members,
+	})
+}
+
+func (h *Handler) UpdateWorkspace(c *fiber.Ctx) error {
+	workspaceID := c.Params("workspaceID")
+	var req WorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	member, err := h.workspaceMember(c, workspaceID, models.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.UserContext()
+	workspace, err := h.store.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		return lookupError(err, ErrWorkspaceNotFound, "Failed to get workspace")
+	}
+	workspace.Name = req.Name
+	if err := h.store.UpdateWorkspace(ctx, workspace); err != nil {
+		return lookupError(err, ErrWorkspaceNotFound, "Failed to update workspace")
+	}
+	return c.JSON(WorkspaceSummary{Workspace: *workspace, Role: member.Role})
+}
+
+// DeleteWorkspace はワークスペースをイベントごと削除する。オーナーのみ実行できる
+func (h *Handler) DeleteWorkspace(c *fiber.Ctx) error {
+	workspaceID := c.Params("workspaceID")
+	if _, err := h.workspaceMember(c, workspaceID, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+	if err := h.store.DeleteWorkspace(c.UserContext(), workspaceID); err != nil {
+		return lookupError(err, ErrWorkspaceNotFound, "Failed to delete workspace")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListWorkspaceEvents はワークスペースのイベントを新しい順に返す
+func (h *Handler) ListWorkspaceEvents(c *fiber.Ctx) error {
+	workspaceID := c.Params("workspaceID")
+	if _, err := h.workspaceMember(c, workspaceID); err != nil {
+		return err
+	}
+	events, err := h.store.ListEventsByWorkspace(c.UserContext(), workspaceID)
+	if err != nil {
+		return internalError("Failed to get events", err)
+	}
+	return h.sendDashboardEvents(c, events)
+}
+
+func (h *Handler) ListWorkspaceMembers(c *fiber.Ctx) error {
+	workspaceID := c.Params("workspaceID")
+	if _, err := h.workspaceMember(c, workspaceID); err != nil {
+		return err
+	}
+	members, err := h.store.ListWorkspaceMembers(c.UserContext(), workspaceID)
+	if err != nil {
+		return internalError("Failed to get workspace members", err)
+	}
+	return c.JSON(members)
+}
+
+// AddWorkspaceMember は登録済みのユーザーをメールアドレスで追加する。オーナーのみ実行できる
+func (h *Handler) AddWorkspaceMember(c *fiber.Ctx) error {
+	workspaceID := c.Params("workspaceID")
+	var req AddWorkspaceMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	req.Email = normalizeEmail(req.Email)
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	if _, err := h.workspaceMember(c, workspaceID, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+
+	ctx := c.UserContext()
+	user, err := h.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return lookupError(err, ErrUserNotFound, "Failed to get user")
+	}
+	member := models.WorkspaceMember{WorkspaceID: workspaceID, UserID: user.ID, Role: req.Role}
+	if err := h.store.AddWorkspaceMember(ctx, &member); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return ErrMemberExists
+		}
+		return internalError("Failed to add workspace member", err)
+	}
+	member.User = user
+	return c.Status(fiber.StatusCreated).JSON(member)
+}
+
+// UpdateWorkspaceMember はメンバーの役割を変更する。オーナーのみ実行できる
+func (h *Handler) UpdateWorkspaceMember(c *fiber.Ctx) error {
+	workspaceID, userID := c.Params("workspaceID"), c.Params("userID")
+	var req UpdateWorkspaceMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return ErrInvalidRequest
+	}
+	if err := validateStruct(req); err != nil {
+		return err
+	}
+	if _, err := h.workspaceMember(c, workspaceID, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+
+	ctx := c.UserContext()
+	member, err := h.store.GetWorkspaceMember(ctx, workspaceID, userID)
+	if err != nil {
+		return lookupError(err, ErrMemberNotFound, "Failed to get workspace member")
+	}
+	if member.Role == models.WorkspaceRoleOwner && req.Role != models.WorkspaceRoleOwner {
+		if err := h.ensureAnotherOwner(ctx, workspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	member.Role = req.Role
+	if err := h.store.UpdateWorkspaceMember(ctx, member); err != nil {
+		return internalError("Failed to update workspace member", err)
+	}
+	return c.JSON(member)
+}
+
+// RemoveWorkspaceMember はメンバーを外す。オーナーは誰でも、それ以外のメンバーは自分自身だけを外せる
+func (h *Handler) RemoveWorkspaceMember(c *fiber.Ctx) error {
+	workspaceID, userID := c.Params("workspaceID"), c.Params("userID")
+	current, err := h.workspaceMember(c, workspaceID)
+	if err != nil {
+		return err
+	}
+	if current.Role != models.WorkspaceRoleOwner && current.UserID != userID {
+		return ErrWorkspaceForbidden
+	}
+
+	ctx := c.UserContext()
+	member, err := h.store.GetWorkspaceMember(ctx, workspaceID, userID)
+	if err != nil {
+		return lookupError(err, ErrMemberNotFound, "Failed to get workspace member")
+	}
+	if member.Role == models.WorkspaceRoleOwner {
+		if err := h.ensureAnotherOwner(ctx, workspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := h.store.RemoveWorkspaceMember(ctx, workspaceID, userID); err != nil {
+		return lookupError(err, ErrMemberNotFound, "Failed to remove workspace member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ensureAnotherOwner は userID 以外にオーナーがいなければ ErrLastOwner を返す
+func (h *Handler) ensureAnotherOwner(ctx context.Context, workspaceID, userID string) error {
+	members, err := h.store.ListWorkspaceMembers(ctx, workspaceID)
+	if err != nil {
+		return internalError("Failed to get workspace members", err)
+	}
+	for _, member := range members {
+		if member.Role == models.WorkspaceRoleOwner && member.UserID != userID {
+			return nil
+		}
+	}
+	return ErrLastOwner
+}
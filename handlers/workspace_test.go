@@ -0,0 +1,149 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"yotei-backend/handlers"
+	"yotei-backend/models"
+)
+
+// createWorkspace は token のユーザーをオーナーとするワークスペースを作成する
+func (ts *testServer) createWorkspace(token, name string) string {
+	ts.t.Helper()
+
+	var workspace handlers.WorkspaceSummary
+	decodeJSON(ts.t, ts.request(http.MethodPost, "/api/v1/workspaces", map[string]string{"name": name}, bearer(token)), http.StatusCreated, &workspace)
+	return workspace.ID
+}
+
+// addMember は email のユーザーを role でワークスペースに追加し、ユーザー ID を返す
+func (ts *testServer) addMember(token, workspaceID, email, role string) string {
+	ts.t.Helper()
+
+	resp := ts.request(http.MethodPost, "/api/v1/workspaces/"+workspaceID+"/members", map[string]string{"email": email, "role": role}, bearer(token))
+	var member models.WorkspaceMember
+	decodeJSON(ts.t, resp, http.StatusCreated, &member)
+	return member.UserID
+}
+
+func TestDeleteEvent(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	owner := ts.signUp("owner@example.com", "Owner")
+	member := ts.signUp("member@example.com", "Member")
+	outsider := ts.signUp("outsider@example.com", "Outsider")
+	workspaceID := ts.createWorkspace(owner, "Team")
+	ts.addMember(owner, workspaceID, "member@example.com", "member")
+
+	date := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	req := eventRequest("Workspace event", date)
+	req["workspace_id"] = workspaceID
+	eventID := ts.createEvent(req, bearer(owner))
+	path := "/api/v1/events/" + eventID
+
+	// メンバー以外にはイベントがないのと同じに見せる
+	expectError(t, ts.request(http.MethodDelete, path, nil, bearer(outsider)), http.StatusNotFound, handlers.CodeEventNotFound)
+	expectError(t, ts.request(http.MethodDelete, path, nil, nil), http.StatusUnauthorized, handlers.CodeUnauthorized)
+	expectError(t, ts.request(http.MethodDelete, path, nil, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+	decodeJSON(t, ts.request(http.MethodDelete, path, nil, bearer(owner)), http.StatusNoContent, nil)
+	expectError(t, ts.request(http.MethodGet, path, nil, bearer(owner)), http.StatusNotFound, handlers.CodeEventNotFound)
+
+	// ワークスペースに属さないイベントは作成者だけが削除できる
+	eventID = ts.createEvent(eventRequest("Public event", date), bearer(owner))
+	expectError(t, ts.request(http.MethodDelete, "/api/v1/events/"+eventID, nil, bearer(outsider)), http.StatusForbidden, handlers.CodeForbidden)
+	decodeJSON(t, ts.request(http.MethodDelete, "/api/v1/events/"+eventID, nil, bearer(owner)), http.StatusNoContent, nil)
+}
+
+func TestWorkspaceRoles(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	owner := ts.signUp("owner@example.com", "Owner")
+	organizer := ts.signUp("organizer@example.com", "Organizer")
+	member := ts.signUp("member@example.com", "Member")
+	outsider := ts.signUp("outsider@example.com", "Outsider")
+	workspaceID := ts.createWorkspace(owner, "Team")
+	ts.addMember(owner, workspaceID, "organizer@example.com", "organizer")
+	memberID := ts.addMember(owner, workspaceID, "member@example.com", "member")
+	path := "/api/v1/workspaces/" + workspaceID
+
+	// メンバー以外にはワークスペースがないのと同じに見せる
+	expectError(t, ts.request(http.MethodGet, path, nil, bearer(outsider)), http.StatusNotFound, handlers.CodeWorkspaceNotFound)
+	expectError(t, ts.request(http.MethodGet, path, nil, nil), http.StatusUnauthorized, handlers.CodeUnauthorized)
+
+	var details handlers.WorkspaceDetails
+	decodeJSON(t, ts.request(http.MethodGet, path, nil, bearer(member)), http.StatusOK, &details)
+	if details.Role != models.WorkspaceRoleMember || len(details.Members) != 3 {
+		t.Fatalf("workspace = role %q with %d members, want member with 3", details.Role, len(details.Members))
+	}
+
+	// イベントを作成できるのはオーナーとオーガナイザーだけ
+	date := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	req := eventRequest("Workspace event", date)
+	req["workspace_id"] = workspaceID
+	expectError(t, ts.request(http.MethodPost, "/api/v1/events", req, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+	expectError(t, ts.request(http.MethodPost, "/api/v1/events", req, bearer(outsider)), http.StatusNotFound, handlers.CodeWorkspaceNotFound)
+	eventID := ts.createEvent(req, bearer(organizer))
+
+	var events []map[string]any
+	decodeJSON(t, ts.request(http.MethodGet, path+"/events", nil, bearer(member)), http.StatusOK, &events)
+	if len(events) != 1 || events[0]["id"] != eventID {
+		t.Fatalf("workspace events = %v, want only %s", events, eventID)
+	}
+	expectError(t, ts.request(http.MethodGet, "/api/v1/events/"+eventID, nil, bearer(outsider)), http.StatusNotFound, handlers.CodeEventNotFound)
+
+	// ワークスペースの変更とメンバーの管理はオーナーだけ
+	rename := map[string]string{"name": "Renamed"}
+	expectError(t, ts.request(http.MethodPut, path, rename, bearer(organizer)), http.StatusForbidden, handlers.CodeForbidden)
+	decodeJSON(t, ts.request(http.MethodPut, path, rename, bearer(owner)), http.StatusOK, nil)
+
+	add := map[string]string{"email": "outsider@example.com", "role": "member"}
+	expectError(t, ts.request(http.MethodPost, path+"/members", add, bearer(organizer)), http.StatusForbidden, handlers.CodeForbidden)
+	expectError(t, ts.request(http.MethodPost, path+"/members", map[string]string{"email": "member@example.com", "role": "member"}, bearer(owner)), http.StatusConflict, handlers.CodeMemberExists)
+	expectError(t, ts.request(http.MethodPost, path+"/members", map[string]string{"email": "nobody@example.com", "role": "member"}, bearer(owner)), http.StatusNotFound, handlers.CodeUserNotFound)
+	expectError(t, ts.request(http.MethodPost, path+"/members", map[string]string{"email": "outsider@example.com", "role": "admin"}, bearer(owner)), http.StatusBadRequest, handlers.CodeValidationFailed)
+
+	promote := map[string]string{"role": "organizer"}
+	expectError(t, ts.request(http.MethodPut, path+"/members/"+memberID, promote, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+	var updated models.WorkspaceMember
+	decodeJSON(t, ts.request(http.MethodPut, path+"/members/"+memberID, promote, bearer(owner)), http.StatusOK, &updated)
+	if updated.Role != models.WorkspaceRoleOrganizer {
+		t.Fatalf("updated role = %q, want organizer", updated.Role)
+	}
+
+	expectError(t, ts.request(http.MethodDelete, path, nil, bearer(organizer)), http.StatusForbidden, handlers.CodeForbidden)
+	decodeJSON(t, ts.request(http.MethodDelete, path, nil, bearer(owner)), http.StatusNoContent, nil)
+	expectError(t, ts.request(http.MethodGet, path, nil, bearer(owner)), http.StatusNotFound, handlers.CodeWorkspaceNotFound)
+}
+
+func TestRemoveWorkspaceMember(t *testing.T) {
+	ts := newTestServer(t, testConfig())
+	owner := ts.signUp("owner@example.com", "Owner")
+	member := ts.signUp("member@example.com", "Member")
+	ts.signUp("other@example.com", "Other")
+	workspaceID := ts.createWorkspace(owner, "Team")
+	memberID := ts.addMember(owner, workspaceID, "member@example.com", "member")
+	otherID := ts.addMember(owner, workspaceID, "other@example.com", "member")
+	path := "/api/v1/workspaces/" + workspaceID
+
+	var details handlers.WorkspaceDetails
+	decodeJSON(t, ts.request(http.MethodGet, path, nil, bearer(owner)), http.StatusOK, &details)
+	var ownerID string
+	for _, m := range details.Members {
+		if m.Role == models.WorkspaceRoleOwner {
+			ownerID = m.UserID
+		}
+	}
+
+	// オーナー以外は自分自身だけを外せる
+	expectError(t, ts.request(http.MethodDelete, path+"/members/"+otherID, nil, bearer(member)), http.StatusForbidden, handlers.CodeForbidden)
+	decodeJSON(t, ts.request(http.MethodDelete, path+"/members/"+memberID, nil, bearer(member)), http.StatusNoContent, nil)
+	expectError(t, ts.request(http.MethodGet, path, nil, bearer(member)), http.StatusNotFound, handlers.CodeWorkspaceNotFound)
+	expectError(t, ts.request(http.MethodDelete, path+"/members/"+memberID, nil, bearer(owner)), http.StatusNotFound, handlers.CodeNotFound)
+
+	// 最後のオーナーは外すことも役割を変えることもできない
+	expectError(t, ts.request(http.MethodDelete, path+"/members/"+ownerID, nil, bearer(owner)), http.StatusConflict, handlers.CodeLastOwner)
+	expectError(t, ts.request(http.MethodPut, path+"/members/"+ownerID, map[string]string{"role": "member"}, bearer(owner)), http.StatusConflict, handlers.CodeLastOwner)
+
+	decodeJSON(t, ts.request(http.MethodPut, path+"/members/"+otherID, map[string]string{"role": "owner"}, bearer(owner)), http.StatusOK, nil)
+	decodeJSON(t, ts.request(http.MethodDelete, path+"/members/"+ownerID, nil, bearer(owner)), http.StatusNoContent, nil)
+}
@@ -0,0 +1,28 @@
+package httperror
+
+import "github.com/gofiber/fiber/v2"
+
+const localKey = "handler_error"
+
+// Middleware はハンドラが返したエラーを handler で一度だけレスポンスにする。
+// ログ・メトリクス・トレースのミドルウェアより内側に登録し、それらが確定したステータスコードを読めるようにする。
+// 元のエラーは Err で取り出せる
+func Middleware(handler fiber.ErrorHandler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if err == nil {
+			return nil
+		}
+		c.Locals(localKey, err)
+		if handlerErr := handler(c, err); handlerErr != nil {
+			_ = c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return nil
+	}
+}
+
+// Err は Middleware が処理したエラーを返す。エラーがなければ nil
+func Err(c *fiber.Ctx) error {
+	err, _ := c.Locals(localKey).(error)
+	return err
+}
@@ -0,0 +1,56 @@
+package httperror
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMiddlewareResolvesErrorOnce(t *testing.T) {
+	calls := 0
+	handler := func(c *fiber.Ctx, err error) error {
+		calls++
+		return c.Status(fiber.StatusTeapot).SendString(err.Error())
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: handler})
+
+	handlerErr := errors.New("boom")
+	var seenStatus int
+	var seenErr, returned error
+	// ログやメトリクスのような外側のミドルウェア
+	app.Use(func(c *fiber.Ctx) error {
+		returned = c.Next()
+		seenStatus = c.Response().StatusCode()
+		seenErr = Err(c)
+		return returned
+	})
+	app.Use(Middleware(handler))
+	app.Get("/fail", func(c *fiber.Ctx) error { return handlerErr })
+	app.Get("/ok", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/fail", nil), -1)
+	if err != nil {
+		t.Fatalf("GET /fail: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusTeapot || seenStatus != fiber.StatusTeapot {
+		t.Errorf("status = %d, seen by the outer middleware = %d; want %d", resp.StatusCode, seenStatus, fiber.StatusTeapot)
+	}
+	if calls != 1 {
+		t.Errorf("error handler called %d times, want 1", calls)
+	}
+	if returned != nil || !errors.Is(seenErr, handlerErr) {
+		t.Errorf("outer middleware got %v from Next and %v from Err", returned, seenErr)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/ok", nil), -1)
+	if err != nil {
+		t.Fatalf("GET /ok: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK || seenErr != nil {
+		t.Errorf("GET /ok: status = %d, Err = %v", resp.StatusCode, seenErr)
+	}
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger は GORM のログを slog に流す。
+// SQL 文はデバッグレベルでのみ出力し、エラーと遅いクエリは常に出力する。
+// バインド変数にはメールアドレスやパスワードのハッシュが含まれるので出力しない
+type GormLogger struct {
+	SlowThreshold time.Duration
+}
+
+func (l GormLogger) LogMode(logger.LogLevel) logger.Interface {
+	// レベルは slog 側の設定に従う
+	return l
+}
+
+// ParamsFilter を実装すると、GORM はバインド変数を埋め込まずに SQL を渡す
+func (l GormLogger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	return sql, nil
+}
+
+func (l GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	slog.InfoContext(ctx, msg, "args", args)
+}
+
+func (l GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	slog.WarnContext(ctx, msg, "args", args)
+}
+
+func (l GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	slog.ErrorContext(ctx, msg, "args", args)
+}
+
+func (l GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+
+	level := slog.LevelDebug
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		// 一意制約の違反は呼び出し側で 409 として扱う
+		level = slog.LevelWarn
+	case err != nil:
+		level = slog.LevelError
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold:
+		level = slog.LevelWarn
+	}
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("elapsed_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	slog.LogAttrs(ctx, level, "database query", attrs...)
+}
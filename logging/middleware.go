@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"yotei-backend/clientip"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware は requestid ミドルウェアが発行したリクエストIDを UserContext に載せ、リクエストごとにアクセスログを出す。
+// requestid ミドルウェアの後に登録する
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+
+		requestID, _ := c.Locals("requestid").(string)
+		ctx := WithRequestID(c.UserContext(), requestID)
+		c.SetUserContext(ctx)
+
+		// エラーは内側の httperror.Middleware がレスポンスにしてあるので、ここではステータスコードを読むだけにする
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		slog.LogAttrs(ctx, level, "request",
+			slog.String("method", strings.Clone(c.Method())),
+			slog.String("path", strings.Clone(c.Path())),
+			slog.Int("status", status),
+			slog.Int64("latency_ms", time.Since(started).Milliseconds()),
+			slog.String("ip", clientip.IP(c)),
+		)
+		return err
+	}
+}
@@ -1,92 +1,137 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
 	"time"
 
+	"yotei-backend/config"
 	"yotei-backend/database"
 	"yotei-backend/handlers"
-
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/joho/godotenv"
-	"github.com/robfig/cron/v3"
+	"yotei-backend/logging"
+	"yotei-backend/oidcauth"
+	"yotei-backend/store"
+	"yotei-backend/telemetry"
 )
 
+const usage = `Usage: yotei-backend [global flags] <command> [arguments]
+
+Global flags:
+  -env-file <path>           dotenv file to load outside production (default .env)
+  -database-url <url>        overrides DATABASE_URL
+  -port <port>               overrides PORT
+  -frontend-url <url>        overrides FRONTEND_URL
+
+Commands:
+  serve                      start the API server and the scheduler (default)
+  migrate [up|down|status]   manage database migrations
+  finalize-due               finalize events whose deadline has passed, once
+  generate-due               create events for series whose next run has passed, once
+  event show <id>            print an event and its vote counts
+  event export <id>          print an event with all related data as JSON
+  purge -older-than <age>    delete events created before the given age (e.g. 90d, 720h) and expired sessions
+  config                     print the effective configuration with secrets redacted
+`
+
+type command func(args []string) error
+
+var commands = map[string]command{
+	"serve":        runServe,
+	"migrate":      runMigrate,
+	"finalize-due": runFinalizeDue,
+	"generate-due": runGenerateDue,
+	"event":        runEvent,
+	"purge":        runPurge,
+	"config":       runConfig,
+}
+
+// 起動時に読み込んだ設定。すべてのサブコマンドで共有する
+var cfg config.Config
+
 func main() {
-	if os.Getenv("ENV") != "production" {
-		if err := godotenv.Load(); err != nil {
-			log.Println("No .env file found")
-		}
+	if len(os.Args) > 1 && (os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help") {
+		fmt.Print(usage)
+		return
 	}
 
-	if err := database.Connect(); err != nil {
-		log.Fatal("Failed to connect to database:", err)
+	loaded, rest, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprint(os.Stderr, usage)
+		fatal("Invalid configuration", err)
 	}
+	cfg = loaded
+
+	// ログは標準エラー出力に出す（event export などのコマンド出力と混ざらないようにする）
+	logging.Setup(os.Stderr, cfg.Log)
 
-	if err := database.Migrate(); err != nil {
-		log.Fatal("Failed to run migrations:", err)
+	// 引数なしで起動した場合はこれまで通りサーバーを起動する
+	name, args := "serve", []string{}
+	if len(rest) > 0 {
+		name, args = rest[0], rest[1:]
 	}
 
-	loc, _ := time.LoadLocation("Asia/Tokyo")
-	c := cron.New(cron.WithLocation(loc))
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		fatal("Unknown command", fmt.Errorf("%s", name))
+	}
 
-	_, err := c.AddFunc("@every 1m", func() {
-		log.Println("Running scheduled job: Checking deadlines...")
-		err := handlers.CheckDeadlinesAndFinalize()
-		if err != nil {
-			log.Println("Failed to check and finalize deadlines:", err)
-		}
-	})
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Failed to add cron job: %v", err)
+		fatal("Failed to set up tracing", err)
 	}
 
-	c.Start()
-	log.Println("Finalize deadlines scheduler started...")
-	defer c.Stop()
-
-	app := fiber.New(fiber.Config{
-		AppName: "Yotei Backend API v1.0.0",
-	})
-
-	app.Use(logger.New())
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
-	}))
-
-	app.Get("/", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"message": "Yotei Backend API is running",
-			"status":  "ok",
-		})
-	})
-
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status": "healthy",
-		})
-	})
-
-	api := app.Group("/api/v1")
-
-	api.Post("/events", handlers.CreateEvent)
-	api.Get("/events/:id", handlers.GetEvent)
-	api.Post("/events/:id/participant", handlers.RegisterParticipant)
-	api.Put("/events/:id/settings", handlers.UpdateEventSettings)
-	api.Get("/rss/:id/feed", handlers.EventRSS)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	err = run(args)
+
+	// 終了前に未送信のスパンを送る
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		slog.Warn("Failed to flush traces", "error", flushErr)
+	}
+
+	if err != nil {
+		fatal("Command failed", err, "command", name)
 	}
+}
+
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append(args, "error", err)...)
+	os.Exit(1)
+}
+
+// サブコマンド共通のデータベース接続
+func openStore() (*handlers.Handler, store.Store, error) {
+	if err := database.Connect(cfg.Database); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	s := store.NewGormStore(database.DB)
+	// 設定の読み込み時に検証済み
+	location, err := time.LoadLocation(cfg.Scheduler.TimeZone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load time zone: %w", err)
+	}
+	handlerConfig := handlers.Config{
+		FrontendURL:               cfg.FrontendURL,
+		TimeZone:                  location,
+		MaxParticipantsPerEvent:   cfg.MaxParticipantsPerEvent,
+		MaxCandidateDatesPerEvent: cfg.MaxCandidateDatesPerEvent,
+		SessionTTL:                cfg.Auth.SessionTTL,
+		CookieSecure:              cfg.Auth.CookieSecure,
+		CookieSameSite:            cfg.Auth.CookieSameSite,
+	}
+	if cfg.OIDC.Enabled() {
+		handlerConfig.OIDC = oidcauth.New(cfg.OIDC)
+	}
+	return handlers.New(s, handlerConfig), s, nil
+}
 
-	log.Printf("Server starting on port %s", port)
-	if err := app.Listen(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+// yotei-backend config
+func runConfig(args []string) error {
+	for _, line := range cfg.Redacted() {
+		fmt.Println(line)
 	}
+	return nil
 }
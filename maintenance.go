@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// yotei-backend finalize-due
+func runFinalizeDue(args []string) error {
+	flags := flag.NewFlagSet("finalize-due", flag.ExitOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	h, _, err := openStore()
+	if err != nil {
+		return err
+	}
+	return h.CheckDeadlinesAndFinalize(context.Background())
+}
+
+// yotei-backend generate-due
+func runGenerateDue(args []string) error {
+	flags := flag.NewFlagSet("generate-due", flag.ExitOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	h, _, err := openStore()
+	if err != nil {
+		return err
+	}
+	return h.GenerateDueSeries(context.Background())
+}
+
+// yotei-backend purge -older-than 90d [-dry-run]
+func runPurge(args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	olderThan := flags.String("older-than", "", "delete events created more than this long ago (e.g. 90d, 720h)")
+	dryRun := flags.Bool("dry-run", false, "only print how many events would be deleted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *olderThan == "" {
+		flags.Usage()
+		return fmt.Errorf("-older-than is required")
+	}
+
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-age)
+
+	_, s, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if *dryRun {
+		count, err := s.CountEventsCreatedBefore(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d event(s) created before %s would be deleted\n", count, cutoff.Format(time.RFC3339))
+		return nil
+	}
+
+	count, err := s.DeleteEventsCreatedBefore(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d event(s) created before %s\n", count, cutoff.Format(time.RFC3339))
+
+	// スケジューラを止めている環境でも期限切れのセッションが残らないようにする
+	sessions, err := s.DeleteExpiredSessions(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d expired session(s)\n", sessions)
+	return nil
+}
+
+// time.ParseDuration に加えて日数（"90d"）を受け付ける。0 以下はすべてのイベントが対象になるのでエラーにする
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age: %s", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age: %s", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90d", 90 * 24 * time.Hour, false},
+		{"720h", 720 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{"0d", 0, true},
+		{"0s", 0, true},
+		{"-1d", 0, true},
+		{"-5h", 0, true},
+		{"d", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseAge(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseAge(%q) = %s, %v; want %s (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "yotei"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	EventsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_created_total",
+		Help:      "Number of events created.",
+	})
+
+	ParticipantsRegistered = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "participants_registered_total",
+		Help:      "Number of participants registered.",
+	})
+
+	// trigger: deadline / auto_decision, outcome: single / tie / no_votes
+	Finalizations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "finalizations_total",
+		Help:      "Number of event finalizations by trigger and outcome.",
+	}, []string{"trigger", "outcome"})
+
+	SchedulerRunDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scheduler_run_duration_seconds",
+		Help:      "Duration of scheduled deadline checks.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	SchedulerRunFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduler_run_failures_total",
+		Help:      "Number of scheduled deadline checks that returned an error.",
+	})
+
+	SchedulerLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "scheduler_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful scheduled deadline check.",
+	})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Number of requests rejected by a rate limit rule.",
+	}, []string{"rule"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		EventsCreated,
+		ParticipantsRegistered,
+		Finalizations,
+		SchedulerRunDuration,
+		SchedulerRunFailures,
+		SchedulerLastSuccess,
+		RateLimited,
+	)
+}
+
+// RegisterDB はコネクションプールの統計情報を公開する
+func RegisterDB(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Handler は /metrics のハンドラ。Authorization: Bearer で token を送ったリクエストにだけ応答する
+func Handler(token string) fiber.Handler {
+	handler := adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	return func(c *fiber.Ctx) error {
+		sent, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return fiber.ErrUnauthorized
+		}
+		return handler(c)
+	}
+}
+
+// Middleware はルートごとのリクエスト数とレイテンシを記録する。
+// ラベルには実際のパスではなくルートのパターン（/api/v1/events/:id など）を使う
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		started := time.Now()
+
+		// エラーは内側の httperror.Middleware がレスポンスにしてあるので、ここではステータスコードを読むだけにする
+		err := c.Next()
+
+		route := c.Route().Path
+		if c.Response().StatusCode() == fiber.StatusNotFound && route == "/" && c.Path() != "/" {
+			route = "unmatched"
+		}
+		method := strings.Clone(c.Method())
+
+		HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Response().StatusCode())).Inc()
+		HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(started).Seconds())
+		return err
+	}
+}
+
+func ObserveSchedulerRun(duration time.Duration, err error) {
+	SchedulerRunDuration.Observe(duration.Seconds())
+	if err != nil {
+		SchedulerRunFailures.Inc()
+		return
+	}
+	SchedulerLastSuccess.SetToCurrentTime()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"yotei-backend/database"
+)
+
+// yotei-backend migrate [up|down|status] [-steps N]
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert with 'down'")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: yotei-backend migrate [up|down|status] [-steps N]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	command := flags.Arg(0)
+	if command == "" {
+		command = "up"
+	}
+	if flags.NArg() > 1 {
+		// "down -steps 2" のようにサブコマンドの後ろにフラグを書けるようにする
+		if err := flags.Parse(flags.Args()[1:]); err != nil {
+			return err
+		}
+	}
+
+	if _, _, err := openStore(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		return database.MigrateUp(ctx)
+	case "down":
+		return database.MigrateDown(ctx, *steps)
+	case "status":
+		statuses, err := database.GetMigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			if s.Applied {
+				state, appliedAt = "applied", s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown migrate command: %s", command)
+	}
+}
@@ -7,22 +7,30 @@ import (
 )
 
 type Event struct {
-	ID                  string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	Title               string    `gorm:"not null;type:varchar(255)" json:"title"`
-	Description         string    `gorm:"type:text" json:"description"`
-	CreatorName         string    `gorm:"type:varchar(100)" json:"creator_name"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
-	DeadlineReached     bool      `gorm:"default:false" json:"deadline_reached"`
-	AutoDecisionReached bool      `gorm:"default:false" json:"auto_decision_reached"`
+	ID          string `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Title       string `gorm:"not null;type:varchar(255)" json:"title"`
+	Description string `gorm:"type:text" json:"description"`
+	CreatorName string `gorm:"type:varchar(100)" json:"creator_name"`
+	// ログインして作成した場合の作成者。匿名で作成したイベントは nil
+	OwnerID *string `gorm:"type:varchar(36);index" json:"owner_id,omitempty"`
+	// ワークスペースのイベントの場合はそのメンバーにだけ公開する
+	WorkspaceID *string `gorm:"type:varchar(36);index" json:"workspace_id,omitempty"`
+	// シリーズから作成したイベントの場合はそのシリーズ
+	SeriesID  *string   `gorm:"type:varchar(36);index" json:"series_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// フラグと設定の列の既定値はマイグレーションで定義する。GORM の default タグを付けると、
+	// 作成時に false や 0 が既定値に置き換わる
+	DeadlineReached     bool `json:"deadline_reached"`
+	AutoDecisionReached bool `json:"auto_decision_reached"`
 
 	// 設定
-	AllowSettingChanges   bool       `gorm:"default:true" json:"allow_setting_changes"`
-	DeadlineEnable        bool       `gorm:"default:false" json:"deadline_enable"`
+	AllowSettingChanges   bool       `json:"allow_setting_changes"`
+	DeadlineEnable        bool       `json:"deadline_enable"`
 	Deadline              *time.Time `gorm:"type:timestamp" json:"deadline"`
-	AutoDecisionEnable    bool       `gorm:"default:false" json:"auto_decision_enable"`
-	AutoDecisionThreshold int        `gorm:"default:0" json:"auto_decision_threshold"`
-	RSSEnabled            bool       `gorm:"default:false" json:"rss_enabled"`
+	AutoDecisionEnable    bool       `json:"auto_decision_enable"`
+	AutoDecisionThreshold int        `json:"auto_decision_threshold"`
+	RSSEnabled            bool       `json:"rss_enabled"`
 
 	// リレーション
 	CandidateDates []CandidateDate `gorm:"foreignKey:EventID;constraint:OnDelete:CASCADE" json:"candidate_dates"`
@@ -42,9 +50,11 @@ type CandidateDate struct {
 }
 
 type Participant struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	EventID   string    `gorm:"not null;type:varchar(36);index" json:"event_id"`
-	Name      string    `gorm:"not null;type:varchar(100)" json:"name"`
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	EventID string `gorm:"not null;type:varchar(36);index;uniqueIndex:idx_participants_event_user" json:"event_id"`
+	Name    string `gorm:"not null;type:varchar(100)" json:"name"`
+	// ログインして回答した場合のユーザー。1イベントにつき1人1回まで
+	UserID    *string   `gorm:"type:varchar(36);uniqueIndex:idx_participants_event_user" json:"user_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
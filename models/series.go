@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// 候補日を作る期間
+const (
+	// 作成日の翌週（月曜始まり）
+	SeriesPeriodNextWeek = "next_week"
+	// 作成日の翌月
+	SeriesPeriodNextMonth = "next_month"
+)
+
+// EventSeries は定期的に同じ形式の日程調整を作成するシリーズ。作成したイベントは SeriesID で紐づく
+type EventSeries struct {
+	ID          string  `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Title       string  `gorm:"not null;type:varchar(255)" json:"title"`
+	Description string  `gorm:"type:text" json:"description"`
+	CreatorName string  `gorm:"type:varchar(100)" json:"creator_name"`
+	OwnerID     *string `gorm:"type:varchar(36);index" json:"owner_id,omitempty"`
+	// 作成したイベントもこのワークスペースに属する
+	WorkspaceID *string `gorm:"type:varchar(36);index" json:"workspace_id,omitempty"`
+
+	// イベントを作成する日時の cron 式（SCHEDULER_TIMEZONE で解釈する）
+	Schedule      string        `gorm:"not null;type:varchar(100)" json:"schedule"`
+	CandidateRule CandidateRule `gorm:"not null;type:text;serializer:json" json:"candidate_rule"`
+	// false の場合は一時停止中
+	Enabled bool `json:"enabled"`
+	// 次にイベントを作成する日時。一時停止から再開した場合は再開時から計算し直す
+	NextRunAt time.Time  `gorm:"index" json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at"`
+
+	// 作成するイベントの設定
+	AllowSettingChanges bool `json:"allow_setting_changes"`
+	DeadlineEnable      bool `json:"deadline_enable"`
+	// 締切を最初の候補日の何日前にするか
+	DeadlineDaysBefore    int  `json:"deadline_days_before"`
+	AutoDecisionEnable    bool `json:"auto_decision_enable"`
+	AutoDecisionThreshold int  `json:"auto_decision_threshold"`
+	RSSEnabled            bool `json:"rss_enabled"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CandidateRule は作成するイベントの候補日の決め方。期間内の指定した曜日の指定した時刻を候補日にする
+type CandidateRule struct {
+	Period string `json:"period"`
+	// next_month の場合に対象にする週。第 n 週は n*7-6 日から n*7 日まで（各曜日の第 n 回目）。空の場合は月全体
+	Weeks []int `json:"weeks,omitempty"`
+	// 0 = 日曜日
+	Weekdays []int `json:"weekdays"`
+	// "19:00" の形式
+	Times []string `json:"times"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type User struct {
+	ID           string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Email        string    `gorm:"not null;uniqueIndex;type:varchar(255)" json:"email"`
+	Name         string    `gorm:"not null;type:varchar(100)" json:"name"`
+	PasswordHash string    `gorm:"type:varchar(255)" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UserIdentity は OpenID Connect のプロバイダ上のアカウントとユーザーの対応。
+// (Issuer, Subject) はプロバイダをまたいで一意になる
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    string    `gorm:"not null;type:varchar(36);index" json:"user_id"`
+	Issuer    string    `gorm:"not null;type:varchar(255);uniqueIndex:idx_user_identities_issuer_subject" json:"issuer"`
+	Subject   string    `gorm:"not null;type:varchar(255);uniqueIndex:idx_user_identities_issuer_subject" json:"subject"`
+	Email     string    `gorm:"type:varchar(255)" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session はログイン中のセッション。ID にはトークンそのものではなくトークンの SHA-256 を保存する
+type Session struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)" json:"-"`
+	UserID    string    `gorm:"not null;type:varchar(36);index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ワークスペース内の役割
+const (
+	// メンバーの管理とワークスペースの削除ができる
+	WorkspaceRoleOwner = "owner"
+	// ワークスペースのイベントを作成・管理できる
+	WorkspaceRoleOrganizer = "organizer"
+	// ワークスペースのイベントの閲覧と回答ができる
+	WorkspaceRoleMember = "member"
+)
+
+// Workspace はイベントを共有するチーム。ワークスペースのイベントはメンバーにだけ公開される
+type Workspace struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Name      string    `gorm:"not null;type:varchar(100)" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID string    `gorm:"primaryKey;type:varchar(36)" json:"workspace_id"`
+	UserID      string    `gorm:"primaryKey;type:varchar(36);index" json:"user_id"`
+	Role        string    `gorm:"not null;type:varchar(20)" json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// リレーション
+	Workspace *Workspace `gorm:"foreignKey:WorkspaceID" json:"workspace,omitempty"`
+	User      *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// CanManageEvents はワークスペースのイベントを作成・管理できる役割かどうか
+func (m *WorkspaceMember) CanManageEvents() bool {
+	return m.Role == WorkspaceRoleOwner || m.Role == WorkspaceRoleOrganizer
+}
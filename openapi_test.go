@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"yotei-backend/docs"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gofiber/fiber/v2"
+)
+
+func init() {
+	openapi3filter.RegisterBodyDecoder("application/rss+xml", openapi3filter.FileBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/csv", openapi3filter.FileBodyDecoder)
+}
+
+// contractClient は server.New のアプリケーションにリクエストを送り、リクエストとレスポンスを OpenAPI の定義と照合する
+type contractClient struct {
+	t      *testing.T
+	app    *fiber.App
+	router routers.Router
+	// 呼び出した操作（"GET /api/v1/events/{id}" の形式）
+	covered map[string]bool
+}
+
+type requestOption func(*http.Request)
+
+func withToken(token string) requestOption {
+	return func(req *http.Request) {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+}
+
+func withHeader(name, value string) requestOption {
+	return func(req *http.Request) {
+		req.Header.Set(name, value)
+	}
+}
+
+// loadOpenAPI は配信している仕様を読み込み、定義のないプロパティを許さないようにする
+func loadOpenAPI(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(docs.OpenAPI)
+	if err != nil {
+		t.Fatalf("load openapi.json: %v", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		t.Fatalf("openapi.json is invalid: %v", err)
+	}
+	// ルーターが相対 URL のサーバーを扱えないので、テストのホストを指定する
+	doc.Servers = openapi3.Servers{{URL: "http://localhost"}}
+
+	strict := map[*openapi3.Schema]bool{}
+	for _, path := range doc.Paths.Map() {
+		for _, op := range path.Operations() {
+			for _, response := range op.Responses.Map() {
+				for _, mediaType := range response.Value.Content {
+					disallowAdditionalProperties(mediaType.Schema, strict)
+				}
+			}
+		}
+	}
+	return doc
+}
+
+// disallowAdditionalProperties はレスポンスに仕様にないプロパティがあれば検証エラーにする。
+// allOf はプロパティをまとめたひとつのオブジェクトにしてから制限する
+func disallowAdditionalProperties(ref *openapi3.SchemaRef, visited map[*openapi3.Schema]bool) {
+	if ref == nil || ref.Value == nil || visited[ref.Value] {
+		return
+	}
+	schema := ref.Value
+	visited[schema] = true
+
+	if len(schema.AllOf) > 0 {
+		properties := openapi3.Schemas{}
+		var required []string
+		for _, part := range schema.AllOf {
+			merged := flattenAllOf(part.Value)
+			for name, property := range merged.Properties {
+				properties[name] = property
+			}
+			required = append(required, merged.Required...)
+		}
+		schema.AllOf = nil
+		schema.Type = &openapi3.Types{openapi3.TypeObject}
+		schema.Properties = properties
+		schema.Required = required
+	}
+
+	if schema.Type.Is(openapi3.TypeObject) && len(schema.Properties) > 0 &&
+		schema.AdditionalProperties.Has == nil && schema.AdditionalProperties.Schema == nil {
+		schema.AdditionalProperties = openapi3.AdditionalProperties{Has: openapi3.Ptr(false)}
+	}
+
+	for _, property := range schema.Properties {
+		disallowAdditionalProperties(property, visited)
+	}
+	disallowAdditionalProperties(schema.Items, visited)
+	disallowAdditionalProperties(schema.AdditionalProperties.Schema, visited)
+	for _, alternative := range schema.OneOf {
+		disallowAdditionalProperties(alternative, visited)
+	}
+	for _, alternative := range schema.AnyOf {
+		disallowAdditionalProperties(alternative, visited)
+	}
+}
+
+// flattenAllOf は allOf を含むスキーマのプロパティと必須項目をまとめる。元のスキーマは変更しない
+func flattenAllOf(schema *openapi3.Schema) *openapi3.Schema {
+	merged := &openapi3.Schema{Properties: openapi3.Schemas{}}
+	for _, part := range schema.AllOf {
+		flattened := flattenAllOf(part.Value)
+		for name, property := range flattened.Properties {
+			merged.Properties[name] = property
+		}
+		merged.Required = append(merged.Required, flattened.Required...)
+	}
+	for name, property := range schema.Properties {
+		merged.Properties[name] = property
+	}
+	merged.Required = append(merged.Required, schema.Required...)
+	return merged
+}
+
+// call はリクエストを送り、ステータスコードが status であることと、仕様どおりであることを確認して本文を返す。
+// 2xx を期待するリクエストは本文やパラメータも仕様と照合する
+func (cc *contractClient) call(method, path string, body any, status int, opts ...requestOption) []byte {
+	cc.t.Helper()
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			cc.t.Fatalf("marshal request body: %v", err)
+		}
+	}
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(method, "http://localhost"+path, bytes.NewReader(payload))
+		if body != nil {
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		}
+		for _, opt := range opts {
+			opt(req)
+		}
+		return req
+	}
+
+	resp, err := cc.app.Test(newRequest(), -1)
+	if err != nil {
+		cc.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		cc.t.Fatalf("%s %s: read response: %v", method, path, err)
+	}
+	if resp.StatusCode != status {
+		cc.t.Fatalf("%s %s: status = %d, want %d; body: %s", method, path, resp.StatusCode, status, respBody)
+	}
+
+	req := newRequest()
+	route, pathParams, err := cc.router.FindRoute(req)
+	if err != nil {
+		cc.t.Fatalf("%s %s: no operation in openapi.json: %v", method, path, err)
+	}
+	cc.covered[method+" "+route.Path] = true
+
+	ctx := context.Background()
+	options := &openapi3filter.Options{
+		IncludeResponseStatus: true,
+		MultiError:            true,
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+	}
+	input := &openapi3filter.RequestValidationInput{Request: req, PathParams: pathParams, Route: route, Options: options}
+	if status < 300 {
+		if err := openapi3filter.ValidateRequest(ctx, input); err != nil {
+			cc.t.Errorf("%s %s: request does not match openapi.json: %v", method, path, err)
+		}
+	}
+	err = openapi3filter.ValidateResponse(ctx, &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Body:                   io.NopCloser(bytes.NewReader(respBody)),
+		Options:                options,
+	})
+	if err != nil {
+		cc.t.Errorf("%s %s: %d response does not match openapi.json: %v\nbody: %s", method, path, resp.StatusCode, err, respBody)
+	}
+	return respBody
+}
+
+// callJSON は call の本文を v に読み込む
+func (cc *contractClient) callJSON(method, path string, body any, status int, v any, opts ...requestOption) {
+	cc.t.Helper()
+
+	respBody := cc.call(method, path, body, status, opts...)
+	if err := json.Unmarshal(respBody, v); err != nil {
+		cc.t.Fatalf("%s %s: decode response %s: %v", method, path, respBody, err)
+	}
+}
+
+func (cc *contractClient) signUp(email, name string) (token string, userID string) {
+	cc.t.Helper()
+
+	var auth struct {
+		Token string `json:"token"`
+		User  struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	}
+	cc.callJSON(http.MethodPost, "/api/v1/auth/signup", map[string]string{
+		"email":    email,
+		"password": "password1234",
+		"name":     name,
+	}, http.StatusCreated, &auth)
+	return auth.Token, auth.User.ID
+}
+
+type idResponse struct {
+	ID string `json:"id"`
+}
+
+// TestOpenAPIContract はすべてのルートを呼び出し、レスポンスが openapi.json の定義と一致することを確認する
+func TestOpenAPIContract(t *testing.T) {
+	app, _ := newTestApp(t, map[string]string{
+		"FRONTEND_URL":  "https://yotei.example.com",
+		"METRICS_TOKEN": "metrics-token",
+	})
+	doc := loadOpenAPI(t)
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("create router: %v", err)
+	}
+	cc := &contractClient{t: t, app: app, router: router, covered: map[string]bool{}}
+
+	// 監視向け
+	cc.call(http.MethodGet, "/", nil, http.StatusOK)
+	cc.call(http.MethodGet, "/health", nil, http.StatusOK)
+	cc.call(http.MethodGet, "/livez", nil, http.StatusOK)
+	cc.call(http.MethodGet, "/readyz", nil, http.StatusOK)
+	cc.call(http.MethodGet, "/status", nil, http.StatusOK)
+	cc.call(http.MethodGet, "/metrics", nil, http.StatusOK, withToken("metrics-token"))
+	cc.call(http.MethodGet, "/metrics", nil, http.StatusUnauthorized)
+	cc.call(http.MethodGet, "/api/v1/openapi.json", nil, http.StatusOK)
+
+	// 認証
+	owner, _ := cc.signUp("owner@example.com", "Owner")
+	organizer, organizerID := cc.signUp("organizer@example.com", "Organizer")
+	cc.call(http.MethodPost, "/api/v1/auth/signup", map[string]string{"email": "owner@example.com", "password": "password1234", "name": "Owner"}, http.StatusConflict)
+	cc.call(http.MethodPost, "/api/v1/auth/signup", map[string]string{"email": "not-an-email", "password": "short"}, http.StatusBadRequest)
+	cc.call(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": "owner@example.com", "password": "password1234"}, http.StatusOK)
+	cc.call(http.MethodPost, "/api/v1/auth/login", map[string]string{"email": "owner@example.com", "password": "wrong-password"}, http.StatusUnauthorized)
+	cc.call(http.MethodGet, "/api/v1/me", nil, http.StatusOK, withToken(owner))
+	cc.call(http.MethodGet, "/api/v1/me", nil, http.StatusUnauthorized)
+	// OpenID Connect は設定していないので 404
+	cc.call(http.MethodGet, "/api/v1/auth/oidc/login", nil, http.StatusNotFound)
+	cc.call(http.MethodGet, "/api/v1/auth/oidc/callback?state=x&code=y", nil, http.StatusNotFound)
+
+	// ワークスペース
+	var workspace idResponse
+	cc.callJSON(http.MethodPost, "/api/v1/workspaces", map[string]string{"name": "Team", "description": "Our team"}, http.StatusCreated, &workspace, withToken(owner))
+	workspacePath := "/api/v1/workspaces/" + workspace.ID
+	cc.call(http.MethodPost, "/api/v1/workspaces", map[string]string{}, http.StatusBadRequest, withToken(owner))
+	cc.call(http.MethodGet, "/api/v1/workspaces", nil, http.StatusOK, withToken(owner))
+	cc.call(http.MethodGet, workspacePath, nil, http.StatusOK, withToken(owner))
+	cc.call(http.MethodGet, workspacePath, nil, http.StatusNotFound, withToken(organizer))
+	cc.call(http.MethodPut, workspacePath, map[string]string{"name": "Team A"}, http.StatusOK, withToken(owner))
+	cc.call(http.MethodPost, workspacePath+"/members", map[string]string{"email": "organizer@example.com", "role": "member"}, http.StatusCreated, withToken(owner))
+	cc.call(http.MethodPost, workspacePath+"/members", map[string]string{"email": "organizer@example.com", "role": "member"}, http.StatusConflict, withToken(owner))
+	cc.call(http.MethodPut, workspacePath+"/members/"+organizerID, map[string]string{"role": "organizer"}, http.StatusOK, withToken(owner))
+	cc.call(http.MethodPut, workspacePath, map[string]string{"name": "Team B"}, http.StatusForbidden, withToken(organizer))
+	cc.call(http.MethodGet, workspacePath+"/members", nil, http.StatusOK, withToken(organizer))
+
+	// シリーズ
+	series := map[string]any{
+		"title":        "Monthly meeting",
+		"workspace_id": workspace.ID,
+		"schedule":     "0 9 1 * *",
+		"candidate_rule": map[string]any{
+			"period":   "next_month",
+			"weeks":    []int{1, 2},
+			"weekdays": []int{1, 3},
+			"times":    []string{"19:00"},
+		},
+		"settings": map[string]any{
+			"allow_setting_changes": true,
+			"deadline_enable":       true,
+			"deadline_days_before":  3,
+			"rss_enabled":           true,
+		},
+	}
+	var createdSeries idResponse
+	cc.callJSON(http.MethodPost, "/api/v1/series", series, http.StatusCreated, &createdSeries, withToken(organizer))
+	seriesPath := "/api/v1/series/" + createdSeries.ID
+	cc.call(http.MethodPost, "/api/v1/series", map[string]any{"title": "No schedule"}, http.StatusBadRequest, withToken(owner))
+	cc.call(http.MethodGet, "/api/v1/series", nil, http.StatusOK, withToken(owner))
+	cc.call(http.MethodGet, seriesPath, nil, http.StatusOK, withToken(owner))
+	series["enabled"] = false
+	delete(series, "workspace_id")
+	cc.call(http.MethodPut, seriesPath, series, http.StatusOK, withToken(organizer))
+	cc.call(http.MethodGet, seriesPath+"/events", nil, http.StatusOK, withToken(owner))
+	cc.call(http.MethodGet, "/api/v1/series/00000000-0000-0000-0000-000000000000", nil, http.StatusNotFound, withToken(owner))
+
+	// イベント
+	first := time.Now().AddDate(0, 1, 0).Truncate(time.Hour).UTC()
+	event := map[string]any{
+		"title":           "Team lunch",
+		"description":     "Where should we go?",
+		"creator_name":    "Organizer",
+		"candidate_dates": []string{first.Format(time.RFC3339), first.AddDate(0, 0, 1).Format(time.RFC3339)},
+		"settings": map[string]any{
+			"allow_setting_changes":   false,
+			"deadline_enable":         true,
+			"deadline":                first.AddDate(0, 0, -7).Format(time.RFC3339),
+			"auto_decision_enable":    true,
+			"auto_decision_threshold": 2,
+			"rss_enabled":             true,
+		},
+	}
+	var created idResponse
+	cc.callJSON(http.MethodPost, "/api/v1/events", event, http.StatusCreated, &created, withToken(owner))
+	eventPath := "/api/v1/events/" + created.ID
+	event["workspace_id"] = workspace.ID
+	var workspaceEvent idResponse
+	cc.callJSON(http.MethodPost, "/api/v1/events", event, http.StatusCreated, &workspaceEvent, withToken(organizer))
+	cc.call(http.MethodPost, "/api/v1/events", map[string]any{"title": ""}, http.StatusBadRequest)
+	cc.call(http.MethodPost, "/api/v1/events", event, http.StatusUnauthorized)
+	cc.call(http.MethodGet, workspacePath+"/events", nil, http.StatusOK, withToken(owner))
+
+	var details struct {
+		CandidateDates []struct {
+			ID uint `json:"id"`
+		} `json:"candidate_dates"`
+	}
+	cc.callJSON(http.MethodGet, eventPath, nil, http.StatusOK, &details)
+	cc.call(http.MethodGet, eventPath+"?include=counts,responses&fields=title,deadline", nil, http.StatusOK)
+	cc.call(http.MethodGet, eventPath+"?include=unknown", nil, http.StatusBadRequest)
+	cc.call(http.MethodGet, "/api/v1/events/00000000-0000-0000-0000-000000000000", nil, http.StatusNotFound)
+	cc.call(http.MethodGet, "/api/v1/events/"+workspaceEvent.ID, nil, http.StatusNotFound)
+
+	vote := func(participantID uint, name string) map[string]any {
+		return map[string]any{
+			"event_id":                    created.ID,
+			"participant_id":              participantID,
+			"name":                        name,
+			"available_candidate_dates":   []map[string]uint{{"id": details.CandidateDates[0].ID}},
+			"unavailable_candidate_dates": []map[string]uint{{"id": details.CandidateDates[1].ID}},
+		}
+	}
+	cc.call(http.MethodPost, eventPath+"/participant", vote(1001, "Alice"), http.StatusCreated)
+	cc.call(http.MethodPost, eventPath+"/participant", vote(1001, "Alice"), http.StatusConflict)
+	cc.call(http.MethodPost, eventPath+"/participant", vote(1002, ""), http.StatusBadRequest)
+	cc.call(http.MethodPost, "/api/v1/events/00000000-0000-0000-0000-000000000000/participant", vote(1002, "Bob"), http.StatusNotFound)
+	// 2人目で自動決定する
+	cc.call(http.MethodPost, eventPath+"/participant", vote(1003, "Carol"), http.StatusCreated, withToken(organizer))
+
+	settings := map[string]any{"allow_setting_changes": true, "rss_enabled": true}
+	cc.call(http.MethodPut, eventPath+"/settings", settings, http.StatusForbidden)
+	cc.call(http.MethodPut, eventPath+"/settings", settings, http.StatusOK, withToken(owner))
+	cc.call(http.MethodPut, eventPath+"/settings", map[string]any{"deadline_enable": true, "deadline": "2000-01-01T00:00:00Z"}, http.StatusBadRequest)
+	cc.call(http.MethodGet, eventPath+"/summary", nil, http.StatusOK)
+	cc.call(http.MethodGet, eventPath+"/export.csv?labels=words&tz=Asia/Tokyo", nil, http.StatusOK)
+	cc.call(http.MethodGet, eventPath+"/export.csv?tz=Nowhere/Unknown", nil, http.StatusBadRequest)
+	cc.call(http.MethodPut, "/api/v1/events/00000000-0000-0000-0000-000000000000/settings", settings, http.StatusNotFound)
+
+	cc.call(http.MethodGet, "/api/v1/rss/"+created.ID+"/feed", nil, http.StatusOK)
+	cc.call(http.MethodGet, "/api/v1/rss/00000000-0000-0000-0000-000000000000/feed", nil, http.StatusNotFound)
+
+	export := cc.call(http.MethodGet, eventPath+"/export", nil, http.StatusOK)
+	var exported map[string]any
+	if err := json.Unmarshal(export, &exported); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	var imported idResponse
+	cc.callJSON(http.MethodPost, "/api/v1/events/import?workspace_id="+workspace.ID, exported, http.StatusCreated, &imported, withToken(organizer))
+	cc.call(http.MethodGet, "/api/v1/events/"+imported.ID, nil, http.StatusNotFound)
+	cc.call(http.MethodGet, "/api/v1/events/"+imported.ID, nil, http.StatusOK, withToken(owner))
+	cc.call(http.MethodPost, "/api/v1/events/import", map[string]any{"version": 1}, http.StatusBadRequest, withToken(owner))
+	cc.call(http.MethodPost, "/api/v1/events/import", exported, http.StatusUnauthorized)
+
+	cc.call(http.MethodPost, eventPath+"/clone", map[string]any{"offset": "7d", "title": "Team lunch (next week)"}, http.StatusCreated)
+	cc.call(http.MethodPost, eventPath+"/clone", map[string]any{"offset": "soon"}, http.StatusBadRequest)
+
+	// ダッシュボードと検索
+	cc.call(http.MethodGet, "/api/v1/me/events", nil, http.StatusOK, withToken(owner))
+	cc.call(http.MethodGet, "/api/v1/me/participations", nil, http.StatusOK, withToken(organizer))
+	var page struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	cc.callJSON(http.MethodGet, "/api/v1/events?limit=1&sort=created_at", nil, http.StatusOK, &page, withToken(organizer))
+	if page.NextCursor == "" {
+		t.Error("search returned no next_cursor for the first of several events")
+	} else {
+		cc.call(http.MethodGet, "/api/v1/events?limit=1&sort=created_at&cursor="+page.NextCursor, nil, http.StatusOK, withToken(organizer))
+	}
+	cc.call(http.MethodGet, "/api/v1/events?status=unknown", nil, http.StatusBadRequest, withToken(owner))
+	cc.call(http.MethodGet, "/api/v1/events", nil, http.StatusUnauthorized)
+
+	// Cookie で認証した JSON 以外の変更系のリクエストは受け付けない
+	cc.call(http.MethodPost, "/api/v1/auth/logout", nil, http.StatusUnsupportedMediaType,
+		withHeader(fiber.HeaderCookie, "yotei_session="+owner), withHeader(fiber.HeaderContentType, "text/plain"))
+
+	// 削除
+	cc.call(http.MethodDelete, eventPath, nil, http.StatusForbidden, withToken(organizer))
+	cc.call(http.MethodDelete, eventPath, nil, http.StatusNoContent, withToken(owner))
+	cc.call(http.MethodDelete, seriesPath, nil, http.StatusNoContent, withToken(owner))
+	cc.call(http.MethodDelete, workspacePath+"/members/"+organizerID, nil, http.StatusNoContent, withToken(organizer))
+	cc.call(http.MethodDelete, workspacePath, nil, http.StatusNoContent, withToken(owner))
+	cc.call(http.MethodDelete, workspacePath, nil, http.StatusNotFound, withToken(owner))
+	cc.call(http.MethodPost, "/api/v1/auth/logout", nil, http.StatusNoContent, withToken(owner))
+
+	// 仕様のすべての操作を呼び出し、登録したすべてのルートが仕様にあること
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !cc.covered[method+" "+path] {
+				t.Errorf("%s %s is documented but was not called", method, path)
+			}
+		}
+	}
+	for _, route := range undocumentedRoutes(app, doc) {
+		t.Errorf("%s is registered but not documented in openapi.json", route)
+	}
+}
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+// undocumentedRoutes は登録したルートのうち、openapi.json に操作のないもの
+func undocumentedRoutes(app *fiber.App, doc *openapi3.T) []string {
+	var missing []string
+	for _, route := range app.GetRoutes(true) {
+		// GET のルートには HEAD も登録される
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		item := doc.Paths.Value(path)
+		if item == nil || item.GetOperation(route.Method) == nil {
+			missing = append(missing, fmt.Sprintf("%s %s", route.Method, path))
+		}
+	}
+	sort.Strings(missing)
+	return slices.Compact(missing)
+}
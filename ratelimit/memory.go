@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 期限切れのカウンタを掃除する間隔
+const sweepInterval = time.Minute
+
+type counter struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryStore はプロセス内でカウントする。レプリカが1台の場合やローカル開発向け
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]*counter{}, lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	start := windowStart(now, window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, c := range s.counters {
+			if !c.resetAt.After(now) {
+				delete(s.counters, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	c, ok := s.counters[key]
+	if !ok || !c.resetAt.After(now) {
+		c = &counter{resetAt: start.Add(window)}
+		s.counters[key] = c
+	}
+	c.count++
+	return c.count, c.resetAt, nil
+}
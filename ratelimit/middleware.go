@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"yotei-backend/clientip"
+	"yotei-backend/config"
+	"yotei-backend/metrics"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rule はひとつの制限。Key が空文字を返したリクエストは制限しない
+type Rule struct {
+	Name string
+	config.RateLimit
+	Key func(c *fiber.Ctx) string
+}
+
+// ByIP はクライアントの IP アドレスごとに制限する
+func ByIP(c *fiber.Ctx) string {
+	return clientip.IP(c)
+}
+
+// ByEvent はパスのイベントIDごとに制限する
+func ByEvent(c *fiber.Ctx) string {
+	return c.Params("id")
+}
+
+// Middleware は制限を超えたリクエストに 429 を返す。
+// ストアのエラーでサービスを止めないよう、エラー時はリクエストを通す
+func Middleware(store Store, rule Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if rule.Limit <= 0 {
+			return c.Next()
+		}
+		key := rule.Key(c)
+		if key == "" {
+			return c.Next()
+		}
+
+		count, resetAt, err := store.Hit(c.UserContext(), rule.Name+":"+key, rule.Window)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "Rate limit check failed", "rule", rule.Name, "error", err)
+			return c.Next()
+		}
+
+		remaining := max(rule.Limit-count, 0)
+		c.Set("X-RateLimit-Limit", strconv.Itoa(rule.Limit))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+		if count > rule.Limit {
+			retryAfter := int(time.Until(resetAt).Seconds()) + 1
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			metrics.RateLimited.WithLabelValues(rule.Name).Inc()
+			slog.WarnContext(c.UserContext(), "Rate limit exceeded", "rule", rule.Name, "key", key)
+			return fiber.NewError(fiber.StatusTooManyRequests, "Too many requests, please try again later")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,17 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store は固定ウィンドウ方式でキーごとのリクエスト数を数える
+type Store interface {
+	// Hit はキーのカウントを1増やし、現在のウィンドウでのカウントとウィンドウの終了時刻を返す
+	Hit(ctx context.Context, key string, window time.Duration) (count int, resetAt time.Time, err error)
+}
+
+// windowStart は now を含むウィンドウの開始時刻。全レプリカで同じ値になるように Unix 時刻で区切る
+func windowStart(now time.Time, window time.Duration) time.Time {
+	return now.Truncate(window)
+}
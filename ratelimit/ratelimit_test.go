@@ -0,0 +1,141 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"yotei-backend/config"
+	"yotei-backend/database"
+	"yotei-backend/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newSQLStore(t *testing.T) *ratelimit.SQLStore {
+	t.Helper()
+
+	err := database.Connect(config.DatabaseConfig{URL: filepath.Join(t.TempDir(), "yotei.db"), ConnectRetries: 1})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("close database: %v", err)
+		}
+	})
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return ratelimit.NewSQLStore(database.DB)
+}
+
+func TestStores(t *testing.T) {
+	stores := []struct {
+		name  string
+		store func(t *testing.T) ratelimit.Store
+	}{
+		{"memory", func(t *testing.T) ratelimit.Store { return ratelimit.NewMemoryStore() }},
+		{"sql", func(t *testing.T) ratelimit.Store { return newSQLStore(t) }},
+	}
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.store(t)
+			ctx := context.Background()
+
+			hit := func(key string, window time.Duration) (int, time.Time) {
+				t.Helper()
+				count, resetAt, err := s.Hit(ctx, key, window)
+				if err != nil {
+					t.Fatalf("Hit(%q): %v", key, err)
+				}
+				return count, resetAt
+			}
+
+			// 1日のウィンドウは UTC の日付で区切る
+			const day = 24 * time.Hour
+			for want := 1; want <= 3; want++ {
+				count, resetAt := hit("ip:203.0.113.1", day)
+				if count != want {
+					t.Errorf("hit %d: count = %d", want, count)
+				}
+				if wantReset := time.Now().Truncate(day).Add(day); !resetAt.Equal(wantReset) {
+					t.Errorf("resetAt = %s, want %s", resetAt, wantReset)
+				}
+			}
+			if count, _ := hit("ip:203.0.113.2", day); count != 1 {
+				t.Errorf("count for another key = %d, want 1", count)
+			}
+
+			// ウィンドウが変わるとカウントをやり直す
+			const window = 200 * time.Millisecond
+			hit("event:1", window)
+			_, resetAt := hit("event:1", window)
+			time.Sleep(time.Until(resetAt) + 10*time.Millisecond)
+			if count, _ := hit("event:1", window); count != 1 {
+				t.Errorf("count in the next window = %d, want 1", count)
+			}
+		})
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	return 0, time.Time{}, errors.New("database is down")
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name  string
+		store ratelimit.Store
+		limit int
+		key   func(c *fiber.Ctx) string
+		want  []int
+		// X-RateLimit-* と Retry-After を付けるか
+		headers bool
+	}{
+		{"limited", ratelimit.NewMemoryStore(), 2, ratelimit.ByIP, []int{200, 200, 429, 429}, true},
+		{"unlimited", ratelimit.NewMemoryStore(), 0, ratelimit.ByIP, []int{200, 200, 200}, false},
+		{"no key", ratelimit.NewMemoryStore(), 1, func(c *fiber.Ctx) string { return "" }, []int{200, 200}, false},
+		{"store error", failingStore{}, 1, ratelimit.ByIP, []int{200, 200}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(ratelimit.Middleware(tt.store, ratelimit.Rule{
+				Name:      "test",
+				RateLimit: config.RateLimit{Limit: tt.limit, Window: time.Hour},
+				Key:       tt.key,
+			}))
+			app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+			for i, want := range tt.want {
+				resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil), -1)
+				if err != nil {
+					t.Fatalf("request %d: %v", i+1, err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != want {
+					t.Fatalf("request %d: status = %d, want %d", i+1, resp.StatusCode, want)
+				}
+				if !tt.headers {
+					if limit := resp.Header.Get("X-RateLimit-Limit"); limit != "" {
+						t.Errorf("request %d: X-RateLimit-Limit = %q, want none", i+1, limit)
+					}
+					continue
+				}
+				if remaining := strconv.Itoa(max(tt.limit-i-1, 0)); resp.Header.Get("X-RateLimit-Remaining") != remaining {
+					t.Errorf("request %d: X-RateLimit-Remaining = %q, want %s", i+1, resp.Header.Get("X-RateLimit-Remaining"), remaining)
+				}
+				if retryAfter := resp.Header.Get(fiber.HeaderRetryAfter); (want == fiber.StatusTooManyRequests) != (retryAfter != "") {
+					t.Errorf("request %d: Retry-After = %q", i+1, retryAfter)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SQLStore は rate_limits テーブルでカウントする。複数のレプリカでカウントを共有する場合に使う
+type SQLStore struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewSQLStore(db *gorm.DB) *SQLStore {
+	return &SQLStore{db: db, lastSweep: time.Now()}
+}
+
+func (s *SQLStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now().UTC()
+	start := windowStart(now, window)
+	resetAt := start.Add(window)
+
+	if err := s.sweep(ctx, now); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	// ウィンドウが変わっていればカウントを1からやり直す
+	var count int
+	err := s.db.WithContext(ctx).Raw(`INSERT INTO rate_limits (limit_key, window_start, expires_at, count) VALUES (?, ?, ?, 1)
+		ON CONFLICT (limit_key) DO UPDATE SET
+			count = CASE WHEN rate_limits.window_start = excluded.window_start THEN rate_limits.count + 1 ELSE 1 END,
+			window_start = excluded.window_start,
+			expires_at = excluded.expires_at
+		RETURNING count`, key, start, resetAt).Scan(&count).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return count, resetAt, nil
+}
+
+// sweep は期限切れの行を定期的に削除する
+func (s *SQLStore) sweep(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	return s.db.WithContext(ctx).Exec("DELETE FROM rate_limits WHERE expires_at <= ?", now).Error
+}
@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"yotei-backend/config"
+	"yotei-backend/metrics"
+	"yotei-backend/telemetry"
+
+	"github.com/robfig/cron/v3"
+)
+
+type Job func(ctx context.Context) error
+
+// Status は直近のジョブ実行結果。/status で公開するため、エラーの内容は含めずログにだけ出す
+type Status struct {
+	Enabled      bool       `json:"enabled"`
+	Spec         string     `json:"spec"`
+	Running      bool       `json:"running"`
+	RunningSince *time.Time `json:"running_since"`
+	// 実行中のジョブがタイムアウトを過ぎても終わっていない
+	Stuck          bool       `json:"stuck"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastSuccessAt  *time.Time `json:"last_success_at"`
+	LastErrorAt    *time.Time `json:"last_error_at"`
+}
+
+// Scheduler は締切チェックなどの定期ジョブを実行し、その状態を記録する
+type Scheduler struct {
+	cron       *cron.Cron
+	job        Job
+	jobTimeout time.Duration
+
+	// 実行中のジョブに渡すコンテキスト。停止がタイムアウトした場合にキャンセルする
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	status Status
+}
+
+func New(cfg config.SchedulerConfig, job Job) (*Scheduler, error) {
+	loc, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	// cron 自体のログ（前回のジョブが実行中でスキップした場合など）も slog に流す
+	cronLogger := cron.PrintfLogger(slog.NewLogLogger(slog.Default().Handler(), slog.LevelInfo))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		cron:       cron.New(cron.WithLocation(loc), cron.WithChain(cron.SkipIfStillRunning(cronLogger))),
+		job:        job,
+		jobTimeout: cfg.JobTimeout,
+		ctx:        ctx,
+		cancel:     cancel,
+		status:     Status{Enabled: cfg.Enabled, Spec: cfg.Spec},
+	}
+
+	if _, err := s.cron.AddFunc(cfg.Spec, func() {
+		_ = s.RunOnce(s.ctx)
+	}); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to add cron job: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Scheduler) Start() {
+	if !s.status.Enabled {
+		return
+	}
+	s.cron.Start()
+	slog.Info("Scheduler started", "spec", s.status.Spec)
+}
+
+// キャンセルしたジョブが終了するまで待つ時間。データベースを閉じる前にジョブの書き込みを終わらせる
+const cancelGracePeriod = 5 * time.Second
+
+// Stop は新しいジョブの起動を止め、実行中のジョブの終了を待つ。
+// ctx が先に終了した場合は実行中のジョブをキャンセルし、cancelGracePeriod まで終了を待つ
+func (s *Scheduler) Stop(ctx context.Context) {
+	done := s.cron.Stop()
+	select {
+	case <-done.Done():
+		s.cancel()
+		return
+	case <-ctx.Done():
+		slog.Warn("Timed out waiting for the scheduled job; cancelling it")
+	}
+	s.cancel()
+
+	select {
+	case <-done.Done():
+	case <-time.After(cancelGracePeriod):
+		slog.Error("Scheduled job did not stop after cancellation", "grace_period", cancelGracePeriod.String())
+	}
+}
+
+// RunOnce はジョブを一度実行し、結果を Status に記録する
+func (s *Scheduler) RunOnce(ctx context.Context) (err error) {
+	ctx, span := telemetry.Start(ctx, "scheduler.run")
+	defer func() { telemetry.End(span, err) }()
+
+	started := time.Now()
+	s.mu.Lock()
+	s.status.Running = true
+	s.status.RunningSince = &started
+	s.mu.Unlock()
+
+	if s.jobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.jobTimeout)
+		defer cancel()
+	}
+	err = s.job(ctx)
+
+	finished := time.Now()
+	metrics.ObserveSchedulerRun(finished.Sub(started), err)
+	if err != nil {
+		slog.ErrorContext(ctx, "Scheduled job failed", "duration_ms", finished.Sub(started).Milliseconds(), "error", err)
+	} else {
+		slog.DebugContext(ctx, "Scheduled job completed", "duration_ms", finished.Sub(started).Milliseconds())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Running = false
+	s.status.RunningSince = nil
+	s.status.LastRunAt = &started
+	s.status.LastDurationMs = finished.Sub(started).Milliseconds()
+	if err != nil {
+		s.status.LastErrorAt = &finished
+	} else {
+		s.status.LastSuccessAt = &finished
+	}
+	return err
+}
+
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	// キャンセルに応じないジョブはタイムアウト後も終わらないので、経過時間から判定する
+	status.Stuck = status.Running && s.jobTimeout > 0 && time.Since(*status.RunningSince) > s.jobTimeout
+	return status
+}
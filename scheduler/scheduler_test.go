@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"yotei-backend/config"
+)
+
+func TestRunOnceTimesOut(t *testing.T) {
+	s, err := New(config.SchedulerConfig{Spec: "@every 1m", TimeZone: "UTC", JobTimeout: 10 * time.Millisecond}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if err := s.RunOnce(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunOnce = %v, want deadline exceeded", err)
+	}
+	if status := s.Status(); status.Running || status.LastErrorAt == nil {
+		t.Errorf("status after timeout = %+v", status)
+	}
+}
+
+func TestStatusReportsStuckJob(t *testing.T) {
+	release := make(chan struct{})
+	// キャンセルを無視するジョブ
+	s, err := New(config.SchedulerConfig{Spec: "@every 1m", TimeZone: "UTC", JobTimeout: 10 * time.Millisecond}, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- s.RunOnce(context.Background()) }()
+
+	deadline := time.Now().Add(time.Second)
+	for !s.Status().Stuck {
+		if time.Now().After(deadline) {
+			t.Fatalf("job was not reported as stuck: %+v", s.Status())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if status := s.Status(); status.Stuck || status.Running {
+		t.Errorf("status after the job finished = %+v", status)
+	}
+}
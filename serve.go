@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"yotei-backend/database"
+	"yotei-backend/handlers"
+	"yotei-backend/metrics"
+	"yotei-backend/scheduler"
+	"yotei-backend/server"
+)
+
+// yotei-backend serve [-migrate=false]
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	migrate := flags.Bool("migrate", true, "apply pending migrations before starting the server")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	slog.Info("Effective configuration", "config", cfg.Redacted())
+
+	h, _, err := openStore()
+	if err != nil {
+		return err
+	}
+
+	if *migrate {
+		if err := database.Migrate(); err != nil {
+			return err
+		}
+	}
+
+	if sqlDB, err := database.DB.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB); err != nil {
+			return err
+		}
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			slog.Error("Failed to close database", "error", err)
+		}
+	}()
+
+	// SIGTERM（App Runner のデプロイ時など）や Ctrl+C で停止処理を始める
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sched, err := scheduler.New(cfg.Scheduler, h.RunScheduledJobs)
+	if err != nil {
+		return err
+	}
+	sched.Start()
+
+	app := server.New(cfg, h, handlers.HealthChecks{
+		Ping:             database.Ping,
+		MigrationVersion: database.MigrationVersion,
+		Scheduler:        sched.Status,
+		Timeout:          cfg.ReadinessTimeout,
+	})
+
+	listenErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server starting", "port", cfg.Port)
+		listenErr <- app.Listen(fmt.Sprintf(":%d", cfg.Port))
+	}()
+
+	select {
+	case err := <-listenErr:
+		sched.Stop(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("Shutting down", "timeout", cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// HTTP は新規接続の受付を止めて処理中のリクエストを待ち、スケジューラは実行中のジョブを待つ
+	if err := app.ShutdownWithContext(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down HTTP server gracefully", "error", err)
+	}
+	sched.Stop(shutdownCtx)
+
+	slog.Info("Shutdown complete")
+	return nil
+}
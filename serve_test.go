@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"yotei-backend/config"
+	"yotei-backend/handlers"
+	"yotei-backend/scheduler"
+	"yotei-backend/server"
+	"yotei-backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTestApp は環境変数 env で読み込んだ設定とメモリのストアで server.New を呼ぶ
+func newTestApp(t *testing.T, env map[string]string) (*fiber.App, *store.MemoryStore) {
+	t.Helper()
+
+	t.Setenv("DATABASE_URL", ":memory:")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	loaded, _, err := config.Load([]string{"-env-file", filepath.Join(t.TempDir(), ".env")})
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg = loaded
+
+	s := store.NewMemoryStore()
+	h := handlers.New(s, handlers.Config{
+		FrontendURL:               cfg.FrontendURL,
+		MaxParticipantsPerEvent:   cfg.MaxParticipantsPerEvent,
+		MaxCandidateDatesPerEvent: cfg.MaxCandidateDatesPerEvent,
+		SessionTTL:                cfg.Auth.SessionTTL,
+		CookieSecure:              cfg.Auth.CookieSecure,
+		CookieSameSite:            cfg.Auth.CookieSameSite,
+	})
+	app := server.New(cfg, h, handlers.HealthChecks{
+		Ping:             func(ctx context.Context) error { return nil },
+		MigrationVersion: func(ctx context.Context) (int, int, error) { return 7, 7, nil },
+		Scheduler:        func() scheduler.Status { return scheduler.Status{Enabled: true, Spec: cfg.Scheduler.Spec} },
+		Timeout:          cfg.ReadinessTimeout,
+	})
+	return app, s
+}
+
+func TestCORS(t *testing.T) {
+	const allowed = "https://yotei.example.com"
+	app, _ := newTestApp(t, map[string]string{
+		"CORS_ALLOW_ORIGINS":     allowed,
+		"CORS_ALLOW_CREDENTIALS": "true",
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		origin      string
+		status      int
+		allowOrigin string
+		credentials string
+		// プリフライトは CORS のミドルウェアが応答するので、セキュリティヘッダは付かない
+		preflight bool
+	}{
+		{"preflight from an allowed origin", http.MethodOptions, allowed, http.StatusNoContent, allowed, "true", true},
+		{"preflight from another origin", http.MethodOptions, "https://evil.example.com", http.StatusNoContent, "", "", true},
+		{"request from an allowed origin", http.MethodGet, allowed, http.StatusOK, allowed, "true", false},
+		{"request from another origin", http.MethodGet, "https://evil.example.com", http.StatusOK, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/auth/me", nil)
+			if !tt.preflight {
+				req = httptest.NewRequest(tt.method, "/health", nil)
+			}
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+				req.Header.Set("Access-Control-Request-Headers", "Content-Type, Authorization")
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			want := map[string]string{
+				"Access-Control-Allow-Origin":      tt.allowOrigin,
+				"Access-Control-Allow-Credentials": tt.credentials,
+			}
+			if tt.preflight {
+				want["Access-Control-Allow-Methods"] = "GET,POST,PUT,DELETE,OPTIONS"
+				want["Access-Control-Allow-Headers"] = "Origin,Content-Type,Accept,Authorization"
+				want["Access-Control-Max-Age"] = "600"
+			} else {
+				want["Content-Security-Policy"] = "default-src 'none'; frame-ancestors 'none'"
+				want["X-Frame-Options"] = "DENY"
+				want["Referrer-Policy"] = "no-referrer"
+				want["X-Content-Type-Options"] = "nosniff"
+				// HTTP の応答には HSTS を付けない
+				want["Strict-Transport-Security"] = ""
+			}
+			if tt.allowOrigin != "" && !tt.preflight {
+				want["Access-Control-Expose-Headers"] = "X-Request-ID,Retry-After,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset"
+			}
+			for header, value := range want {
+				if got := resp.Header.Get(header); got != value {
+					t.Errorf("%s = %q, want %q", header, got, value)
+				}
+			}
+			// オリジンごとに応答が変わるので、キャッシュが取り違えないよう Vary に含める
+			vary := strings.Split(resp.Header.Get("Vary"), ",")
+			for i := range vary {
+				vary[i] = strings.TrimSpace(vary[i])
+			}
+			if !slices.Contains(vary, "Origin") {
+				t.Errorf("Vary = %q, want Origin", vary)
+			}
+		})
+	}
+}
+
+func TestCORSDefaultsToAnyOrigin(t *testing.T) {
+	app, _ := newTestApp(t, nil)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/events", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	// app.Test の接続元は 0.0.0.0 なので、これを信頼するプロキシにして X-Forwarded-Proto を使わせる
+	env := map[string]string{"TRUSTED_PROXIES": "0.0.0.0", "HSTS_MAX_AGE": "3600"}
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		proto string
+		want  string
+	}{
+		{"https", env, "https", "max-age=3600; includeSubDomains"},
+		{"http", env, "http", ""},
+		{"untrusted proxy", map[string]string{"TRUSTED_PROXIES": "10.0.0.1", "HSTS_MAX_AGE": "3600"}, "https", ""},
+		{"disabled", map[string]string{"TRUSTED_PROXIES": "0.0.0.0", "HSTS_MAX_AGE": "0"}, "https", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newTestApp(t, tt.env)
+
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.Header.Set("X-Forwarded-Proto", tt.proto)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if got := resp.Header.Get("Strict-Transport-Security"); got != tt.want {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{"not configured", "", "", http.StatusNotFound},
+		{"not configured with a token", "", "Bearer metrics-token", http.StatusNotFound},
+		{"missing token", "metrics-token", "", http.StatusUnauthorized},
+		{"wrong token", "metrics-token", "Bearer other-token", http.StatusUnauthorized},
+		{"not a bearer token", "metrics-token", "metrics-token", http.StatusUnauthorized},
+		{"valid token", "metrics-token", "Bearer metrics-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newTestApp(t, map[string]string{"METRICS_TOKEN": tt.token})
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.header)
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("GET /metrics: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"strings"
+
+	"yotei-backend/clientip"
+	"yotei-backend/config"
+	"yotei-backend/database"
+	"yotei-backend/docs"
+	"yotei-backend/handlers"
+	"yotei-backend/httperror"
+	"yotei-backend/logging"
+	"yotei-backend/metrics"
+	"yotei-backend/ratelimit"
+	"yotei-backend/telemetry"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/helmet"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// New はミドルウェアとルートを登録したアプリケーションを作る
+func New(cfg config.Config, h *handlers.Handler, health handlers.HealthChecks) *fiber.App {
+	// 形式は設定の読み込み時に確認済み
+	trustedProxies, _ := clientip.ParseTrustedProxies(cfg.TrustedProxies)
+
+	app := fiber.New(fiber.Config{
+		AppName:               "Yotei Backend API v1.0.0",
+		ErrorHandler:          handlers.ErrorHandler,
+		DisableStartupMessage: true,
+		BodyLimit:             cfg.BodyLimit,
+		// c.IP() などがプロキシのヘッダを使うのは、接続元が信頼するプロキシの場合だけにする
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.TrustedProxies,
+	})
+
+	// レート制限やログで使うクライアントの IP アドレスを先に求める
+	app.Use(clientip.Middleware(cfg.ProxyHeader, trustedProxies))
+	app.Use(requestid.New())
+	app.Use(telemetry.Middleware())
+	app.Use(logging.Middleware())
+	app.Use(metrics.Middleware())
+	// エラーはここで一度だけレスポンスにし、外側のミドルウェアは確定したステータスコードを記録する
+	app.Use(httperror.Middleware(handlers.ErrorHandler))
+	app.Use(cors.New(cors.Config{
+		AllowOrigins:     strings.Join(cfg.CORS.AllowOrigins, ", "),
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		ExposeHeaders:    "X-Request-ID, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset",
+		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+		// プリフライトの結果をブラウザに10分キャッシュさせる
+		MaxAge: 600,
+	}))
+	// API は HTML を返さないので、CSP ではすべての読み込みとフレームへの埋め込みを禁止する
+	app.Use(helmet.New(helmet.Config{
+		ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+		XFrameOptions:         "DENY",
+		ReferrerPolicy:        "no-referrer",
+		HSTSMaxAge:            cfg.Security.HSTSMaxAge,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"message": "Yotei Backend API is running",
+			"status":  "ok",
+		})
+	})
+
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"status": "healthy",
+		})
+	})
+	app.Get("/livez", health.Livez)
+	app.Get("/readyz", health.Readyz)
+	app.Get("/status", health.Status)
+	// メトリクスは運用者向けなので、トークンを設定した場合だけ公開する
+	if cfg.Security.MetricsToken != "" {
+		app.Get("/metrics", metrics.Handler(cfg.Security.MetricsToken))
+	}
+
+	api := app.Group("/api/v1")
+
+	api.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return c.Send(docs.OpenAPI)
+	})
+
+	// ログインは任意。セッションがあればユーザーを読み込む
+	api.Use(h.Authenticate)
+
+	limits := newRateLimitStore(cfg.RateLimit)
+	createEventPerIP := ratelimit.Middleware(limits, ratelimit.Rule{Name: "create_event_per_ip", RateLimit: cfg.RateLimit.CreateEventPerIP, Key: ratelimit.ByIP})
+	registerPerIP := ratelimit.Middleware(limits, ratelimit.Rule{Name: "register_per_ip", RateLimit: cfg.RateLimit.RegisterPerIP, Key: ratelimit.ByIP})
+	registerPerEvent := ratelimit.Middleware(limits, ratelimit.Rule{Name: "register_per_event", RateLimit: cfg.RateLimit.RegisterPerEvent, Key: ratelimit.ByEvent})
+	authPerIP := ratelimit.Middleware(limits, ratelimit.Rule{Name: "auth_per_ip", RateLimit: cfg.RateLimit.AuthPerIP, Key: ratelimit.ByIP})
+
+	api.Post("/auth/signup", authPerIP, h.SignUp)
+	api.Post("/auth/login", authPerIP, h.Login)
+	api.Post("/auth/logout", h.Logout)
+	api.Get("/auth/oidc/login", authPerIP, h.OIDCLogin)
+	api.Get("/auth/oidc/callback", h.OIDCCallback)
+	api.Get("/me", h.RequireUser, h.Me)
+	api.Get("/me/events", h.RequireUser, h.ListMyEvents)
+	api.Get("/me/participations", h.RequireUser, h.ListMyParticipations)
+
+	api.Post("/workspaces", h.RequireUser, h.CreateWorkspace)
+	api.Get("/workspaces", h.RequireUser, h.ListMyWorkspaces)
+	api.Get("/workspaces/:workspaceID", h.RequireUser, h.GetWorkspace)
+	api.Put("/workspaces/:workspaceID", h.RequireUser, h.UpdateWorkspace)
+	api.Delete("/workspaces/:workspaceID", h.RequireUser, h.DeleteWorkspace)
+	api.Get("/workspaces/:workspaceID/events", h.RequireUser, h.ListWorkspaceEvents)
+	api.Get("/workspaces/:workspaceID/members", h.RequireUser, h.ListWorkspaceMembers)
+	api.Post("/workspaces/:workspaceID/members", h.RequireUser, h.AddWorkspaceMember)
+	api.Put("/workspaces/:workspaceID/members/:userID", h.RequireUser, h.UpdateWorkspaceMember)
+	api.Delete("/workspaces/:workspaceID/members/:userID", h.RequireUser, h.RemoveWorkspaceMember)
+
+	api.Post("/series", h.RequireUser, h.CreateSeries)
+	api.Get("/series", h.RequireUser, h.ListMySeries)
+	api.Get("/series/:seriesID", h.RequireUser, h.GetSeries)
+	api.Put("/series/:seriesID", h.RequireUser, h.UpdateSeries)
+	api.Delete("/series/:seriesID", h.RequireUser, h.DeleteSeries)
+	api.Get("/series/:seriesID/events", h.RequireUser, h.ListSeriesEvents)
+
+	api.Get("/events", h.RequireUser, h.ListEvents)
+	api.Post("/events", createEventPerIP, h.CreateEvent)
+	api.Post("/events/import", h.RequireUser, createEventPerIP, h.ImportEvent)
+	api.Get("/events/:id", h.GetEvent)
+	api.Get("/events/:id/summary", h.GetEventSummary)
+	api.Get("/events/:id/export.csv", h.ExportEventCSV)
+	api.Get("/events/:id/export", h.ExportEvent)
+	api.Post("/events/:id/clone", createEventPerIP, h.CloneEvent)
+	api.Post("/events/:id/participant", registerPerIP, registerPerEvent, h.RegisterParticipant)
+	api.Put("/events/:id/settings", h.UpdateEventSettings)
+	api.Delete("/events/:id", h.RequireUser, h.DeleteEvent)
+	api.Get("/rss/:id/feed", h.EventRSS)
+
+	return app
+}
+
+// 複数のレプリカで動かす場合は RATE_LIMIT_STORE=database でカウントをデータベースで共有する
+func newRateLimitStore(cfg config.RateLimitConfig) ratelimit.Store {
+	if cfg.Store == "database" {
+		return ratelimit.NewSQLStore(database.DB)
+	}
+	return ratelimit.NewMemoryStore()
+}
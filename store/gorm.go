@@ -0,0 +1,705 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"yotei-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *GormStore) CreateEvent(ctx context.Context, event *models.Event) error {
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createEvent(tx, event)
+	}))
+}
+
+// createEvent はイベントを作成する。false や 0 の設定もそのまま1回で書き込む
+func createEvent(tx *gorm.DB, event *models.Event, omit ...string) error {
+	return tx.Omit(omit...).Create(event).Error
+}
+
+func (s *GormStore) GetEvent(ctx context.Context, id string) (*models.Event, error) {
+	var event models.Event
+	if err := s.db.WithContext(ctx).First(&event, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &event, nil
+}
+
+func (s *GormStore) GetEventDetails(ctx context.Context, id string) (*models.Event, error) {
+	var event models.Event
+	if err := s.db.WithContext(ctx).
+		Preload("CandidateDates").
+		Preload("CandidateDates.Responses").
+		Preload("Participants").
+		Preload("Participants.Responses").
+		First(&event, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &event, nil
+}
+
+func (s *GormStore) UpdateEvent(ctx context.Context, event *models.Event) error {
+	return translateError(s.db.WithContext(ctx).Omit(clause.Associations).Save(event).Error)
+}
+
+func (s *GormStore) CountEventsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Event{}).Where("created_at < ?", cutoff).Count(&count).Error
+	return count, translateError(err)
+}
+
+func (s *GormStore) DeleteEventsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var deleted int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = deleteEvents(tx, "created_at < ?", cutoff)
+		return err
+	})
+	return deleted, translateError(err)
+}
+
+func (s *GormStore) DeleteEvent(ctx context.Context, id string) error {
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		deleted, err := deleteEvents(tx, "id = ?", id)
+		if err == nil && deleted == 0 {
+			return ErrNotFound
+		}
+		return err
+	}))
+}
+
+func (s *GormStore) ImportEvent(ctx context.Context, event *models.Event, feeds []models.RSSFeed, preserveIDs bool) error {
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := createEvent(tx, event, clause.Associations); err != nil {
+			return err
+		}
+
+		candidateDateIDs := make(map[uint]uint, len(event.CandidateDates))
+		for i := range event.CandidateDates {
+			candidateDate := &event.CandidateDates[i]
+			original := candidateDate.ID
+			if !preserveIDs {
+				candidateDate.ID = 0
+			}
+			candidateDate.EventID = event.ID
+			if err := tx.Omit(clause.Associations).Create(candidateDate).Error; err != nil {
+				return err
+			}
+			candidateDateIDs[original] = candidateDate.ID
+		}
+
+		for i := range event.Participants {
+			participant := &event.Participants[i]
+			if !preserveIDs {
+				participant.ID = 0
+			}
+			participant.EventID = event.ID
+			if err := tx.Omit(clause.Associations).Create(participant).Error; err != nil {
+				return err
+			}
+			for j := range participant.Responses {
+				response := &participant.Responses[j]
+				if !preserveIDs {
+					response.ID = 0
+				}
+				response.ParticipantID = participant.ID
+				response.CandidateDateID = candidateDateIDs[response.CandidateDateID]
+			}
+			if len(participant.Responses) > 0 {
+				if err := tx.Create(&participant.Responses).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for i := range feeds {
+			if !preserveIDs {
+				feeds[i].ID = 0
+			}
+			feeds[i].EventID = event.ID
+		}
+		if len(feeds) > 0 {
+			if err := tx.Create(&feeds).Error; err != nil {
+				return err
+			}
+		}
+
+		// ID を指定して作成した場合だけ、次に自動で振られる ID と重ならないようにする
+		if preserveIDs {
+			return syncSequences(tx)
+		}
+		return nil
+	}))
+}
+
+// syncSequences は PostgreSQL の連番を各テーブルの最大の ID まで進める。
+// ID を指定して INSERT してもシーケンスは進まないため、次に自動で振られる ID が重複しないようにする。
+// setval はトランザクションに含まれず、並行する INSERT がすでに nextval で取得した値もあるので、戻すことはしない
+func syncSequences(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, table := range []string{"candidate_dates", "participants", "responses", "rss_feeds"} {
+		var sequence string
+		if err := tx.Raw("SELECT pg_get_serial_sequence(?, 'id')", table).Scan(&sequence).Error; err != nil {
+			return err
+		}
+		query := fmt.Sprintf("SELECT setval('%[1]s', GREATEST((SELECT last_value FROM %[1]s), (SELECT COALESCE(MAX(id), 0) FROM %[2]s)))", sequence, table)
+		if err := tx.Exec(query).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteEvents は条件に一致するイベントを削除する。
+// 外部キー制約の有無に関わらず削除できるよう、子テーブルから順に削除する
+func deleteEvents(tx *gorm.DB, query string, args ...any) (int64, error) {
+	eventIDs := tx.Model(&models.Event{}).Select("id").Where(query, args...)
+	participantIDs := tx.Model(&models.Participant{}).Select("id").Where("event_id IN (?)", eventIDs)
+	candidateDateIDs := tx.Unscoped().Model(&models.CandidateDate{}).Select("id").Where("event_id IN (?)", eventIDs)
+
+	steps := []*gorm.DB{
+		tx.Where("participant_id IN (?) OR candidate_date_id IN (?)", participantIDs, candidateDateIDs).Delete(&models.Response{}),
+		tx.Where("event_id IN (?)", eventIDs).Delete(&models.Participant{}),
+		tx.Unscoped().Where("event_id IN (?)", eventIDs).Delete(&models.CandidateDate{}),
+		tx.Where("event_id IN (?)", eventIDs).Delete(&models.RSSFeed{}),
+	}
+	for _, step := range steps {
+		if step.Error != nil {
+			return 0, step.Error
+		}
+	}
+
+	result := tx.Where(query, args...).Delete(&models.Event{})
+	return result.RowsAffected, result.Error
+}
+
+func (s *GormStore) ListEventsAwaitingDeadline(ctx context.Context) ([]models.Event, error) {
+	var events []models.Event
+	err := s.db.WithContext(ctx).
+		Where("deadline_enable = ? AND deadline_reached = ?", true, false).
+		Find(&events).Error
+	return events, translateError(err)
+}
+
+// timeExpr は日時の列やプレースホルダを比較できる式にする。
+// SQLite は日時を文字列で保存し、タイムゾーンのオフセットも異なりうるので julianday で比較する
+func (s *GormStore) timeExpr(column string) string {
+	if s.db.Dialector.Name() == "sqlite" {
+		return "julianday(" + column + ")"
+	}
+	return column
+}
+
+func (s *GormStore) ListEvents(ctx context.Context, q EventQuery) ([]models.Event, error) {
+	db := s.db.WithContext(ctx).Model(&models.Event{})
+	timeExpr := s.timeExpr
+
+	if q.VisibleTo != "" {
+		db = db.Where("owner_id = ? OR workspace_id IN (?)", q.VisibleTo,
+			s.db.Model(&models.WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", q.VisibleTo))
+	}
+	if q.OwnerID != "" {
+		db = db.Where("owner_id = ?", q.OwnerID)
+	}
+	if q.WorkspaceID != "" {
+		db = db.Where("workspace_id = ?", q.WorkspaceID)
+	}
+	switch q.Status {
+	case StatusOpen:
+		db = db.Where("deadline_reached = ? AND auto_decision_reached = ?", false, false)
+	case StatusDecided:
+		db = db.Where("deadline_reached = ? OR auto_decision_reached = ?", true, true)
+	}
+	if q.Title != "" {
+		db = db.Where(`LOWER(title) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(q.Title))+"%")
+	}
+	for _, r := range []struct {
+		column, op string
+		value      *time.Time
+	}{
+		{"created_at", ">=", q.CreatedAfter},
+		{"created_at", "<", q.CreatedBefore},
+		{"deadline", ">=", q.DeadlineAfter},
+		{"deadline", "<", q.DeadlineBefore},
+	} {
+		if r.value != nil {
+			db = db.Where(fmt.Sprintf("%s %s %s", timeExpr(r.column), r.op, timeExpr("?")), r.value.UTC())
+		}
+	}
+
+	key := timeExpr("created_at")
+	var cursorValue any
+	switch q.Sort {
+	case SortDeadline:
+		key = timeExpr("deadline")
+		db = db.Where("deadline IS NOT NULL")
+	case SortTitle:
+		key = "title"
+	}
+	if q.After != nil {
+		cursorValue = q.After.Title
+		placeholder := "?"
+		if q.Sort != SortTitle {
+			cursorValue, placeholder = q.After.Time.UTC(), timeExpr("?")
+		}
+		op := ">"
+		if q.Desc {
+			op = "<"
+		}
+		db = db.Where(fmt.Sprintf("%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND id %[2]s ?)", key, op, placeholder),
+			cursorValue, cursorValue, q.After.ID)
+	}
+
+	direction := " ASC"
+	if q.Desc {
+		direction = " DESC"
+	}
+	var events []models.Event
+	err := db.Order(key + direction).Order("id" + direction).Limit(q.Limit).Find(&events).Error
+	return events, translateError(err)
+}
+
+// escapeLike は LIKE のワイルドカードをエスケープする
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (s *GormStore) ListEventsByOwner(ctx context.Context, ownerID string) ([]models.Event, error) {
+	var events []models.Event
+	err := s.db.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Order("created_at DESC").
+		Find(&events).Error
+	return events, translateError(err)
+}
+
+func (s *GormStore) ListEventsByWorkspace(ctx context.Context, workspaceID string) ([]models.Event, error) {
+	var events []models.Event
+	err := s.db.WithContext(ctx).
+		Where("workspace_id = ?", workspaceID).
+		Order("created_at DESC").
+		Find(&events).Error
+	return events, translateError(err)
+}
+
+func (s *GormStore) ListEventsByParticipant(ctx context.Context, userID string) ([]models.Event, error) {
+	var events []models.Event
+	err := s.db.WithContext(ctx).
+		Joins("JOIN participants ON participants.event_id = events.id").
+		Where("participants.user_id = ?", userID).
+		Order("participants.created_at DESC").
+		Find(&events).Error
+	return events, translateError(err)
+}
+
+func (s *GormStore) ListCandidateDates(ctx context.Context, eventID string) ([]models.CandidateDate, error) {
+	var candidateDates []models.CandidateDate
+	err := s.db.WithContext(ctx).
+		Where("event_id = ?", eventID).
+		Order("id").
+		Find(&candidateDates).Error
+	return candidateDates, translateError(err)
+}
+
+func (s *GormStore) TallyResponses(ctx context.Context, eventID string) ([]CandidateDateTally, error) {
+	var tallies []CandidateDateTally
+	err := s.db.WithContext(ctx).Model(&models.CandidateDate{}).
+		Select(`candidate_dates.id AS candidate_date_id, candidate_dates.date_time,
+			COUNT(CASE WHEN responses.status = 'available' THEN 1 END) AS available,
+			COUNT(CASE WHEN responses.status = 'maybe' THEN 1 END) AS maybe,
+			COUNT(CASE WHEN responses.status = 'unavailable' THEN 1 END) AS unavailable`).
+		Joins("LEFT JOIN responses ON responses.candidate_date_id = candidate_dates.id").
+		Where("candidate_dates.event_id = ?", eventID).
+		Group("candidate_dates.id, candidate_dates.date_time").
+		Order("candidate_dates.id").
+		Scan(&tallies).Error
+	return tallies, translateError(err)
+}
+
+func (s *GormStore) CreateParticipant(ctx context.Context, participant *models.Participant, limit int) error {
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if limit > 0 {
+			// 同時に登録されても上限を超えないよう、数えてから作成するまでイベントをロックする
+			if err := lockEvent(tx, participant.EventID); err != nil {
+				return err
+			}
+			var count int64
+			if err := tx.Model(&models.Participant{}).Where("event_id = ?", participant.EventID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(limit) {
+				return ErrLimitReached
+			}
+		}
+		return tx.Create(participant).Error
+	}))
+}
+
+// lockEvent はトランザクションが終わるまで、同じイベントをロックする他のトランザクションを待たせる。
+// PostgreSQL では行をロックし、行ロックのない SQLite では更新で先にデータベースの書き込みロックを取る
+func lockEvent(tx *gorm.DB, eventID string) error {
+	var result *gorm.DB
+	if tx.Dialector.Name() == "postgres" {
+		var ids []string
+		result = tx.Model(&models.Event{}).
+			Clauses(clause.Locking{Strength: "NO KEY UPDATE"}).
+			Where("id = ?", eventID).
+			Pluck("id", &ids)
+	} else {
+		result = tx.Model(&models.Event{}).Where("id = ?", eventID).UpdateColumn("updated_at", gorm.Expr("updated_at"))
+	}
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (s *GormStore) ListParticipants(ctx context.Context, eventID string, withResponses bool) ([]models.Participant, error) {
+	db := s.db.WithContext(ctx)
+	if withResponses {
+		db = db.Preload("Responses", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+	}
+	var participants []models.Participant
+	err := db.Where("event_id = ?", eventID).Order("id").Find(&participants).Error
+	return participants, translateError(err)
+}
+
+func (s *GormStore) CountParticipants(ctx context.Context, eventID string) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Participant{}).Where("event_id = ?", eventID).Count(&count).Error
+	return count, translateError(err)
+}
+
+func (s *GormStore) CountParticipantsByEvent(ctx context.Context, eventIDs []string) (map[string]int64, error) {
+	var rows []struct {
+		EventID string
+		Count   int64
+	}
+	err := s.db.WithContext(ctx).Model(&models.Participant{}).
+		Select("event_id, COUNT(*) AS count").
+		Where("event_id IN ?", eventIDs).
+		Group("event_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.EventID] = row.Count
+	}
+	return counts, nil
+}
+
+func (s *GormStore) ListFeeds(ctx context.Context, eventID string) ([]models.RSSFeed, error) {
+	var feeds []models.RSSFeed
+	err := s.db.WithContext(ctx).Where("event_id = ?", eventID).Find(&feeds).Error
+	return feeds, translateError(err)
+}
+
+func (s *GormStore) RecordDecision(ctx context.Context, eventID string, flag DecisionFlag, feed *models.RSSFeed) error {
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 同時に決定した別のリクエストやレプリカと通知が重複しないよう、フラグが立っていない場合だけ更新する
+		result := tx.Model(&models.Event{}).
+			Where("id = ? AND "+string(flag)+" = ?", eventID, false).
+			UpdateColumn(string(flag), true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrConflict
+		}
+		return tx.Create(feed).Error
+	}))
+}
+
+func (s *GormStore) CreateUser(ctx context.Context, user *models.User) error {
+	return translateError(s.db.WithContext(ctx).Create(user).Error)
+}
+
+func (s *GormStore) GetUser(ctx context.Context, id string) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (s *GormStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, "email = ?", email).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (s *GormStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).
+		Joins("JOIN user_identities ON user_identities.user_id = users.id").
+		Where("user_identities.issuer = ? AND user_identities.subject = ?", issuer, subject).
+		First(&user).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (s *GormStore) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	return translateError(s.db.WithContext(ctx).Create(identity).Error)
+}
+
+func (s *GormStore) CreateWorkspace(ctx context.Context, workspace *models.Workspace, ownerID string) error {
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      ownerID,
+			Role:        models.WorkspaceRoleOwner,
+		}).Error
+	}))
+}
+
+func (s *GormStore) GetWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := s.db.WithContext(ctx).First(&workspace, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &workspace, nil
+}
+
+func (s *GormStore) UpdateWorkspace(ctx context.Context, workspace *models.Workspace) error {
+	return translateError(s.db.WithContext(ctx).Save(workspace).Error)
+}
+
+func (s *GormStore) DeleteWorkspace(ctx context.Context, id string) error {
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := deleteEvents(tx, "workspace_id = ?", id); err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", id).Delete(&models.EventSeries{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", id).Delete(&models.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.Workspace{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return result.Error
+	}))
+}
+
+func (s *GormStore) AddWorkspaceMember(ctx context.Context, member *models.WorkspaceMember) error {
+	return translateError(s.db.WithContext(ctx).Omit(clause.Associations).Create(member).Error)
+}
+
+func (s *GormStore) GetWorkspaceMember(ctx context.Context, workspaceID, userID string) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	err := s.db.WithContext(ctx).
+		First(&member, "workspace_id = ? AND user_id = ?", workspaceID, userID).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &member, nil
+}
+
+func (s *GormStore) UpdateWorkspaceMember(ctx context.Context, member *models.WorkspaceMember) error {
+	result := s.db.WithContext(ctx).Model(&models.WorkspaceMember{}).
+		Where("workspace_id = ? AND user_id = ?", member.WorkspaceID, member.UserID).
+		Update("role", member.Role)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return translateError(result.Error)
+}
+
+func (s *GormStore) RemoveWorkspaceMember(ctx context.Context, workspaceID, userID string) error {
+	result := s.db.WithContext(ctx).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Delete(&models.WorkspaceMember{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return translateError(result.Error)
+}
+
+func (s *GormStore) ListWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	var members []models.WorkspaceMember
+	err := s.db.WithContext(ctx).
+		Preload("User").
+		Where("workspace_id = ?", workspaceID).
+		Order("created_at, user_id").
+		Find(&members).Error
+	return members, translateError(err)
+}
+
+func (s *GormStore) ListWorkspacesByUser(ctx context.Context, userID string) ([]models.WorkspaceMember, error) {
+	var memberships []models.WorkspaceMember
+	err := s.db.WithContext(ctx).
+		Preload("Workspace").
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&memberships).Error
+	return memberships, translateError(err)
+}
+
+func (s *GormStore) CreateSeries(ctx context.Context, series *models.EventSeries) error {
+	return translateError(s.db.WithContext(ctx).Create(series).Error)
+}
+
+func (s *GormStore) GetSeries(ctx context.Context, id string) (*models.EventSeries, error) {
+	var series models.EventSeries
+	if err := s.db.WithContext(ctx).First(&series, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &series, nil
+}
+
+// 利用者が変更できる項目。next_run_at と last_run_at はスケジューラも更新するので含めない
+var seriesEditableColumns = []string{
+	"title", "description", "schedule", "candidate_rule", "enabled",
+	"allow_setting_changes", "deadline_enable", "deadline_days_before",
+	"auto_decision_enable", "auto_decision_threshold", "rss_enabled", "updated_at",
+}
+
+func (s *GormStore) UpdateSeries(ctx context.Context, series *models.EventSeries, reschedule bool) error {
+	columns := seriesEditableColumns
+	if reschedule {
+		columns = append(slices.Clone(columns), "next_run_at")
+	}
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(series).Select(columns).Updates(series)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		// スケジューラが進めた next_run_at と last_run_at を返す
+		return tx.Take(series, "id = ?", series.ID).Error
+	}))
+}
+
+func (s *GormStore) DeleteSeries(ctx context.Context, id string) error {
+	return translateError(s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Event{}).Where("series_id = ?", id).UpdateColumn("series_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.EventSeries{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return result.Error
+	}))
+}
+
+func (s *GormStore) ListSeries(ctx context.Context, visibleTo string) ([]models.EventSeries, error) {
+	var series []models.EventSeries
+	err := s.db.WithContext(ctx).
+		Where("owner_id = ? OR workspace_id IN (?)", visibleTo,
+			s.db.Model(&models.WorkspaceMember{}).Select("workspace_id").Where("user_id = ?", visibleTo)).
+		Order("created_at, id").
+		Find(&series).Error
+	return series, translateError(err)
+}
+
+func (s *GormStore) ListDueSeries(ctx context.Context, now time.Time) ([]models.EventSeries, error) {
+	var series []models.EventSeries
+	err := s.db.WithContext(ctx).
+		Where("enabled = ?", true).
+		Where(fmt.Sprintf("%s <= %s", s.timeExpr("next_run_at"), s.timeExpr("?")), now.UTC()).
+		Order("next_run_at").
+		Find(&series).Error
+	return series, translateError(err)
+}
+
+func (s *GormStore) CreateSeriesEvent(ctx context.Context, series *models.EventSeries, event *models.Event, nextRunAt time.Time) error {
+	now := time.Now()
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 複数のレプリカで動かしても1回分のイベントは1つだけ作成する
+		result := tx.Model(&models.EventSeries{}).
+			Where(fmt.Sprintf("id = ? AND %s = %s", s.timeExpr("next_run_at"), s.timeExpr("?")), series.ID, series.NextRunAt.UTC()).
+			UpdateColumns(map[string]any{"next_run_at": nextRunAt.UTC(), "last_run_at": now.UTC()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConflict
+		}
+		if event == nil {
+			return nil
+		}
+		return createEvent(tx, event)
+	})
+	if err != nil {
+		return translateError(err)
+	}
+	series.NextRunAt, series.LastRunAt = nextRunAt, &now
+	return nil
+}
+
+func (s *GormStore) ListEventsBySeries(ctx context.Context, seriesID string) ([]models.Event, error) {
+	var events []models.Event
+	err := s.db.WithContext(ctx).
+		Where("series_id = ?", seriesID).
+		Order("created_at DESC").
+		Find(&events).Error
+	return events, translateError(err)
+}
+
+func (s *GormStore) CreateSession(ctx context.Context, session *models.Session) error {
+	return translateError(s.db.WithContext(ctx).Create(session).Error)
+}
+
+func (s *GormStore) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := s.db.WithContext(ctx).First(&session, "id = ?", id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &session, nil
+}
+
+func (s *GormStore) DeleteSession(ctx context.Context, id string) error {
+	return translateError(s.db.WithContext(ctx).Delete(&models.Session{}, "id = ?", id).Error)
+}
+
+func (s *GormStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where(fmt.Sprintf("%s <= %s", s.timeExpr("expires_at"), s.timeExpr("?")), now.UTC()).
+		Delete(&models.Session{})
+	return result.RowsAffected, translateError(result.Error)
+}
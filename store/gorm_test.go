@@ -0,0 +1,463 @@
+package store_test
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"yotei-backend/config"
+	"yotei-backend/database"
+	"yotei-backend/models"
+	"yotei-backend/store"
+)
+
+// newGormStore は一時ファイルの SQLite にマイグレーションを適用して GormStore を作る
+func newGormStore(t *testing.T) *store.GormStore {
+	t.Helper()
+
+	err := database.Connect(config.DatabaseConfig{URL: filepath.Join(t.TempDir(), "yotei.db"), ConnectRetries: 1})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("close database: %v", err)
+		}
+	})
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return store.NewGormStore(database.DB)
+}
+
+func createUser(t *testing.T, s store.Store, id string) *models.User {
+	t.Helper()
+
+	user := &models.User{ID: id, Email: id + "@example.com", Name: id}
+	if err := s.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func TestGormStoreParticipantsAndTally(t *testing.T) {
+	s := newGormStore(t)
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+
+	first := time.Date(2030, 1, 10, 19, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	event := &models.Event{
+		ID:    "event-1",
+		Title: "Team lunch",
+		// false は GORM の既定値（true）で上書きされないこと
+		AllowSettingChanges: false,
+		CandidateDates: []models.CandidateDate{
+			{EventID: "event-1", DateTime: first},
+			{EventID: "event-1", DateTime: first.AddDate(0, 0, 1)},
+			{EventID: "event-1", DateTime: first.AddDate(0, 0, 2)},
+		},
+	}
+	if err := s.CreateEvent(ctx, event); err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+	got, err := s.GetEvent(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	if got.AllowSettingChanges {
+		t.Error("allow_setting_changes = true, want false")
+	}
+	ids := []uint{event.CandidateDates[0].ID, event.CandidateDates[1].ID, event.CandidateDates[2].ID}
+
+	participants := []models.Participant{
+		{ID: 10, Name: "Alice", UserID: &user.ID, Responses: []models.Response{
+			{CandidateDateID: ids[0], Status: "available"},
+			{CandidateDateID: ids[1], Status: "maybe"},
+		}},
+		{ID: 11, Name: "Bob", Responses: []models.Response{
+			{CandidateDateID: ids[0], Status: "available"},
+			{CandidateDateID: ids[1], Status: "unavailable"},
+		}},
+	}
+	for i := range participants {
+		participants[i].EventID = event.ID
+		if err := s.CreateParticipant(ctx, &participants[i], 2); err != nil {
+			t.Fatalf("CreateParticipant(%s): %v", participants[i].Name, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		participant models.Participant
+		limit       int
+		want        error
+	}{
+		{"over the limit", models.Participant{ID: 12, EventID: event.ID, Name: "Carol"}, 2, store.ErrLimitReached},
+		{"duplicate id", models.Participant{ID: 10, EventID: event.ID, Name: "Alice"}, 0, store.ErrConflict},
+		{"same user twice", models.Participant{ID: 13, EventID: event.ID, Name: "Alice", UserID: &user.ID}, 0, store.ErrConflict},
+		{"unknown event", models.Participant{ID: 14, EventID: "missing", Name: "Dave"}, 2, store.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.CreateParticipant(ctx, &tt.participant, tt.limit); !errors.Is(err, tt.want) {
+				t.Errorf("CreateParticipant = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// 候補日だけを返し、回答は読み込まない
+	candidateDates, err := s.ListCandidateDates(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("ListCandidateDates: %v", err)
+	}
+	if len(candidateDates) != len(ids) {
+		t.Fatalf("ListCandidateDates returned %d dates, want %d", len(candidateDates), len(ids))
+	}
+	for i, candidateDate := range candidateDates {
+		if candidateDate.ID != ids[i] || candidateDate.Responses != nil {
+			t.Errorf("candidate date %d = %+v, want ID %d without responses", i, candidateDate, ids[i])
+		}
+	}
+
+	tallies, err := s.TallyResponses(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("TallyResponses: %v", err)
+	}
+	want := []store.CandidateDateTally{
+		{CandidateDateID: ids[0], DateTime: first, Available: 2},
+		{CandidateDateID: ids[1], DateTime: first.AddDate(0, 0, 1), Maybe: 1, Unavailable: 1},
+		{CandidateDateID: ids[2], DateTime: first.AddDate(0, 0, 2)},
+	}
+	if len(tallies) != len(want) {
+		t.Fatalf("tallies = %+v, want %+v", tallies, want)
+	}
+	for i := range want {
+		got := tallies[i]
+		if got.CandidateDateID != want[i].CandidateDateID || !got.DateTime.Equal(want[i].DateTime) ||
+			got.Available != want[i].Available || got.Maybe != want[i].Maybe || got.Unavailable != want[i].Unavailable {
+			t.Errorf("tallies[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	count, err := s.CountParticipants(ctx, event.ID)
+	if err != nil || count != 2 {
+		t.Errorf("CountParticipants = %d, %v; want 2", count, err)
+	}
+
+	// 決定の通知とフラグは一緒に保存する。2回目は通知を作成しない
+	feed := &models.RSSFeed{EventID: event.ID, Title: event.Title, Link: "https://yotei.example.com", Description: "decided"}
+	if err := s.RecordDecision(ctx, event.ID, store.DecisionAutoDecision, feed); err != nil {
+		t.Fatalf("RecordDecision: %v", err)
+	}
+	again := &models.RSSFeed{EventID: event.ID, Title: event.Title, Link: "https://yotei.example.com", Description: "decided again"}
+	if err := s.RecordDecision(ctx, event.ID, store.DecisionAutoDecision, again); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("RecordDecision twice = %v, want ErrConflict", err)
+	}
+	feeds, err := s.ListFeeds(ctx, event.ID)
+	if err != nil || len(feeds) != 1 || feeds[0].Description != "decided" {
+		t.Errorf("ListFeeds = %+v, %v", feeds, err)
+	}
+	if got, err := s.GetEvent(ctx, event.ID); err != nil || !got.AutoDecisionReached || got.DeadlineReached || got.Title != event.Title {
+		t.Errorf("GetEvent after RecordDecision = %+v, %v", got, err)
+	}
+	if err := s.RecordDecision(ctx, "missing", store.DecisionDeadline, &models.RSSFeed{EventID: "missing"}); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("RecordDecision(missing) = %v, want ErrConflict", err)
+	}
+
+	// 削除すると候補日・参加者・回答・通知も消える
+	if err := s.DeleteEvent(ctx, event.ID); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if tallies, err := s.TallyResponses(ctx, event.ID); err != nil || len(tallies) != 0 {
+		t.Errorf("TallyResponses after delete = %+v, %v", tallies, err)
+	}
+	if err := s.DeleteEvent(ctx, event.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteEvent twice = %v, want ErrNotFound", err)
+	}
+}
+
+// listAll は q.Limit 件ずつ最後のページまでカーソルで読み進め、イベント ID を順に返す
+func listAll(t *testing.T, s store.Store, q store.EventQuery) []string {
+	t.Helper()
+
+	var ids []string
+	for range 100 {
+		events, err := s.ListEvents(context.Background(), q)
+		if err != nil {
+			t.Fatalf("ListEvents(%+v): %v", q, err)
+		}
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		if len(events) < q.Limit {
+			return ids
+		}
+		q.After = q.CursorFor(&events[len(events)-1])
+	}
+	t.Fatal("ListEvents did not reach the last page")
+	return nil
+}
+
+func TestGormStoreListEventsPaging(t *testing.T) {
+	s := newGormStore(t)
+	ctx := context.Background()
+	owner := createUser(t, s, "owner")
+	member := createUser(t, s, "member")
+	other := createUser(t, s, "other")
+
+	workspace := &models.Workspace{ID: "workspace-1", Name: "Team"}
+	if err := s.CreateWorkspace(ctx, workspace, owner.ID); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	if err := s.AddWorkspaceMember(ctx, &models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: member.ID, Role: models.WorkspaceRoleMember}); err != nil {
+		t.Fatalf("AddWorkspaceMember: %v", err)
+	}
+
+	// 日時はタイムゾーンを混ぜて保存する。SQLite では文字列の順と日時の順が一致しない
+	jst := time.FixedZone("JST", 9*60*60)
+	west := time.FixedZone("-0100", -60*60)
+	base := time.Date(2030, 1, 10, 10, 0, 0, 0, time.UTC)
+	at := func(t time.Time) *time.Time { return &t }
+	events := []models.Event{
+		{ID: "e1", Title: "Lunch", CreatedAt: base, Deadline: at(base.AddDate(0, 0, 3))},
+		// e1 と同じ日時
+		{ID: "e2", Title: "dinner", CreatedAt: base.In(jst), Deadline: at(base.AddDate(0, 0, 2).In(jst))},
+		{ID: "e3", Title: "Breakfast", CreatedAt: base.Add(-30 * time.Minute).In(jst), Deadline: at(base.AddDate(0, 0, 2).Add(time.Hour).In(west))},
+		{ID: "e4", Title: "50% off party", CreatedAt: base.Add(time.Hour), WorkspaceID: &workspace.ID, AutoDecisionReached: true},
+		{ID: "e5", Title: "Lunch again", CreatedAt: base.Add(3 * time.Hour).In(west), Deadline: at(base.AddDate(0, 0, 1))},
+		// 他のユーザーのイベントは含まない
+		{ID: "e6", Title: "Other", CreatedAt: base, OwnerID: &other.ID},
+	}
+	for i := range events {
+		if events[i].OwnerID == nil && events[i].WorkspaceID == nil {
+			events[i].OwnerID = &owner.ID
+		}
+		events[i].DeadlineEnable = events[i].Deadline != nil
+		if err := s.CreateEvent(ctx, &events[i]); err != nil {
+			t.Fatalf("CreateEvent(%s): %v", events[i].ID, err)
+		}
+	}
+
+	// 期待する順は Go で並べ替えて求める
+	sorted := func(keep func(models.Event) bool, compare func(a, b models.Event) int, desc bool) []string {
+		var ids []string
+		matched := slices.DeleteFunc(slices.Clone(events), func(e models.Event) bool { return !keep(e) })
+		slices.SortFunc(matched, func(a, b models.Event) int {
+			c := cmp.Or(compare(a, b), cmp.Compare(a.ID, b.ID))
+			if desc {
+				return -c
+			}
+			return c
+		})
+		for _, event := range matched {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+	visibleToOwner := func(e models.Event) bool { return e.ID != "e6" }
+	byCreatedAt := func(a, b models.Event) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	byDeadline := func(a, b models.Event) int { return a.Deadline.Compare(*b.Deadline) }
+	byTitle := func(a, b models.Event) int { return cmp.Compare(a.Title, b.Title) }
+
+	after := base.Add(-time.Minute)
+	tests := []struct {
+		name  string
+		query store.EventQuery
+		want  []string
+	}{
+		{
+			name:  "created_at",
+			query: store.EventQuery{VisibleTo: owner.ID},
+			want:  sorted(visibleToOwner, byCreatedAt, false),
+		},
+		{
+			name:  "created_at desc",
+			query: store.EventQuery{VisibleTo: owner.ID, Desc: true},
+			want:  sorted(visibleToOwner, byCreatedAt, true),
+		},
+		{
+			name:  "deadline",
+			query: store.EventQuery{VisibleTo: owner.ID, Sort: store.SortDeadline},
+			want:  sorted(func(e models.Event) bool { return visibleToOwner(e) && e.Deadline != nil }, byDeadline, false),
+		},
+		{
+			name:  "deadline desc",
+			query: store.EventQuery{VisibleTo: owner.ID, Sort: store.SortDeadline, Desc: true},
+			want:  sorted(func(e models.Event) bool { return visibleToOwner(e) && e.Deadline != nil }, byDeadline, true),
+		},
+		{
+			name:  "title",
+			query: store.EventQuery{VisibleTo: owner.ID, Sort: store.SortTitle},
+			want:  sorted(visibleToOwner, byTitle, false),
+		},
+		{
+			name:  "created after",
+			query: store.EventQuery{VisibleTo: owner.ID, CreatedAfter: &after},
+			want:  sorted(func(e models.Event) bool { return visibleToOwner(e) && !e.CreatedAt.Before(after) }, byCreatedAt, false),
+		},
+		{
+			name:  "title with a LIKE wildcard",
+			query: store.EventQuery{VisibleTo: owner.ID, Title: "50%"},
+			want:  []string{"e4"},
+		},
+		{
+			name:  "title is case insensitive",
+			query: store.EventQuery{VisibleTo: owner.ID, Title: "LUNCH"},
+			want:  []string{"e1", "e5"},
+		},
+		{
+			name:  "decided",
+			query: store.EventQuery{VisibleTo: owner.ID, Status: store.StatusDecided},
+			want:  []string{"e4"},
+		},
+		{
+			name:  "workspace member",
+			query: store.EventQuery{VisibleTo: member.ID},
+			want:  []string{"e4"},
+		},
+	}
+	for _, tt := range tests {
+		for _, limit := range []int{1, 2, 10} {
+			t.Run(fmt.Sprintf("%s/limit %d", tt.name, limit), func(t *testing.T) {
+				tt.query.Limit = limit
+				if got := listAll(t, s, tt.query); !slices.Equal(got, tt.want) {
+					t.Errorf("ids = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestGormStoreListEventsAwaitingDeadline(t *testing.T) {
+	s := newGormStore(t)
+	ctx := context.Background()
+
+	deadline := time.Now().Add(time.Hour)
+	events := []models.Event{
+		{ID: "awaiting", Title: "Awaiting", DeadlineEnable: true, Deadline: &deadline},
+		{ID: "no-deadline", Title: "No deadline"},
+		{ID: "reached", Title: "Reached", DeadlineEnable: true, Deadline: &deadline, DeadlineReached: true},
+	}
+	for i := range events {
+		if err := s.CreateEvent(ctx, &events[i]); err != nil {
+			t.Fatalf("CreateEvent(%s): %v", events[i].ID, err)
+		}
+	}
+
+	awaiting, err := s.ListEventsAwaitingDeadline(ctx)
+	if err != nil {
+		t.Fatalf("ListEventsAwaitingDeadline: %v", err)
+	}
+	if len(awaiting) != 1 || awaiting[0].ID != "awaiting" {
+		t.Errorf("events = %+v, want only the awaiting one", awaiting)
+	}
+}
+
+func TestGormStoreDeleteExpiredSessions(t *testing.T) {
+	s := newGormStore(t)
+	ctx := context.Background()
+	user := createUser(t, s, "alice")
+
+	now := time.Now()
+	jst := time.FixedZone("JST", 9*60*60)
+	sessions := map[string]time.Time{
+		"expired":        now.Add(-time.Hour),
+		"expired in jst": now.Add(-time.Minute).In(jst),
+		"active":         now.Add(time.Hour),
+		"active in jst":  now.Add(time.Minute).In(jst),
+	}
+	for id, expiresAt := range sessions {
+		if err := s.CreateSession(ctx, &models.Session{ID: id, UserID: user.ID, ExpiresAt: expiresAt}); err != nil {
+			t.Fatalf("CreateSession(%s): %v", id, err)
+		}
+	}
+
+	deleted, err := s.DeleteExpiredSessions(ctx, now)
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteExpiredSessions = %d, %v; want 2", deleted, err)
+	}
+	for id, expiresAt := range sessions {
+		_, err := s.GetSession(ctx, id)
+		if expiresAt.After(now) && err != nil {
+			t.Errorf("GetSession(%s) = %v, want the session", id, err)
+		}
+		if !expiresAt.After(now) && !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("GetSession(%s) = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestGormStoreSeries(t *testing.T) {
+	s := newGormStore(t)
+	ctx := context.Background()
+	owner := createUser(t, s, "owner")
+
+	nextRunAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	series := &models.EventSeries{
+		ID:            "series-1",
+		Title:         "Monthly meeting",
+		OwnerID:       &owner.ID,
+		Schedule:      "0 9 1 * *",
+		CandidateRule: models.CandidateRule{Period: models.SeriesPeriodNextMonth, Weeks: []int{1}, Weekdays: []int{1}, Times: []string{"19:00"}},
+		Enabled:       true,
+		NextRunAt:     nextRunAt,
+	}
+	if err := s.CreateSeries(ctx, series); err != nil {
+		t.Fatalf("CreateSeries: %v", err)
+	}
+
+	due, err := s.ListDueSeries(ctx, time.Now())
+	if err != nil || len(due) != 1 {
+		t.Fatalf("ListDueSeries = %+v, %v", due, err)
+	}
+
+	// スケジューラが作成したら、同じ回を別のプロセスが作成しようとしても ErrConflict
+	following := nextRunAt.AddDate(0, 1, 0)
+	event := &models.Event{ID: "series-event-1", Title: series.Title, OwnerID: &owner.ID, SeriesID: &series.ID}
+	if err := s.CreateSeriesEvent(ctx, &due[0], event, following); err != nil {
+		t.Fatalf("CreateSeriesEvent: %v", err)
+	}
+	stale := *series
+	if err := s.CreateSeriesEvent(ctx, &stale, nil, following); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("CreateSeriesEvent for the same run = %v, want ErrConflict", err)
+	}
+
+	// 古い値のまま編集しても、スケジューラが進めた next_run_at は戻らない
+	stale.Title = "Monthly sync"
+	if err := s.UpdateSeries(ctx, &stale, false); err != nil {
+		t.Fatalf("UpdateSeries: %v", err)
+	}
+	got, err := s.GetSeries(ctx, series.ID)
+	if err != nil {
+		t.Fatalf("GetSeries: %v", err)
+	}
+	if got.Title != "Monthly sync" || !got.NextRunAt.Equal(following) || got.LastRunAt == nil {
+		t.Errorf("series = %+v, want the new title and next_run_at %s", got, following)
+	}
+	if !stale.NextRunAt.Equal(following) {
+		t.Errorf("UpdateSeries did not reload next_run_at: %s", stale.NextRunAt)
+	}
+
+	events, err := s.ListEventsBySeries(ctx, series.ID)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("ListEventsBySeries = %+v, %v", events, err)
+	}
+
+	// シリーズを削除してもイベントは残る
+	if err := s.DeleteSeries(ctx, series.ID); err != nil {
+		t.Fatalf("DeleteSeries: %v", err)
+	}
+	remaining, err := s.GetEvent(ctx, event.ID)
+	if err != nil || remaining.SeriesID != nil {
+		t.Errorf("event after deleting the series = %+v, %v", remaining, err)
+	}
+	missing := *series
+	if err := s.UpdateSeries(ctx, &missing, false); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("UpdateSeries after delete = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,988 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"yotei-backend/models"
+)
+
+// MemoryStore はデータベースを使わずに動作する Store の実装。テストやローカルでの確認用。
+// fiber の c.Params の値はリクエスト後に書き換わるバッファを指すので、URL から受け取る ID は複製して保持する
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	events         map[string]models.Event
+	candidateDates map[uint]models.CandidateDate
+	participants   map[uint]models.Participant
+	responses      map[uint]models.Response
+	feeds          map[uint]models.RSSFeed
+	users          map[string]models.User
+	sessions       map[string]models.Session
+	identities     map[uint]models.UserIdentity
+	workspaces     map[string]models.Workspace
+	members        map[memberKey]models.WorkspaceMember
+	series         map[string]models.EventSeries
+
+	lastCandidateDateID uint
+	lastParticipantID   uint
+	lastResponseID      uint
+	lastFeedID          uint
+	lastIdentityID      uint
+}
+
+type memberKey struct {
+	workspaceID string
+	userID      string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		events:         map[string]models.Event{},
+		candidateDates: map[uint]models.CandidateDate{},
+		participants:   map[uint]models.Participant{},
+		responses:      map[uint]models.Response{},
+		feeds:          map[uint]models.RSSFeed{},
+		users:          map[string]models.User{},
+		sessions:       map[string]models.Session{},
+		identities:     map[uint]models.UserIdentity{},
+		workspaces:     map[string]models.Workspace{},
+		members:        map[memberKey]models.WorkspaceMember{},
+		series:         map[string]models.EventSeries{},
+	}
+}
+
+func nextID(last *uint, requested uint) uint {
+	if requested == 0 {
+		*last++
+		return *last
+	}
+	if requested > *last {
+		*last = requested
+	}
+	return requested
+}
+
+// cloneID はクエリパラメータなどから受け取った ID を複製する
+func cloneID(id *string) *string {
+	if id == nil {
+		return nil
+	}
+	cloned := strings.Clone(*id)
+	return &cloned
+}
+
+func sortedValues[K comparable, V any](m map[K]V, keep func(V) bool, less func(a, b V) int) []V {
+	values := []V{}
+	for _, v := range m {
+		if keep(v) {
+			values = append(values, v)
+		}
+	}
+	slices.SortFunc(values, less)
+	return values
+}
+
+func byResponseID(a, b models.Response) int { return cmp.Compare(a.ID, b.ID) }
+
+func (s *MemoryStore) CreateEvent(ctx context.Context, event *models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createEvent(event)
+}
+
+func (s *MemoryStore) createEvent(event *models.Event) error {
+	if _, ok := s.events[event.ID]; ok {
+		return ErrConflict
+	}
+
+	now := time.Now()
+	event.WorkspaceID = cloneID(event.WorkspaceID)
+	event.CreatedAt, event.UpdatedAt = now, now
+	for i := range event.CandidateDates {
+		candidateDate := &event.CandidateDates[i]
+		if _, ok := s.candidateDates[candidateDate.ID]; ok && candidateDate.ID != 0 {
+			return ErrConflict
+		}
+		candidateDate.ID = nextID(&s.lastCandidateDateID, candidateDate.ID)
+		candidateDate.EventID = event.ID
+		candidateDate.CreatedAt, candidateDate.UpdatedAt = now, now
+		stored := *candidateDate
+		stored.Responses = nil
+		s.candidateDates[stored.ID] = stored
+	}
+
+	stored := *event
+	stored.CandidateDates = nil
+	stored.Participants = nil
+	s.events[event.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) GetEvent(ctx context.Context, id string) (*models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &event, nil
+}
+
+func (s *MemoryStore) GetEventDetails(ctx context.Context, id string) (*models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	event.CandidateDates = s.candidateDatesOf(id)
+	event.Participants = s.participantsOf(id)
+	return &event, nil
+}
+
+func (s *MemoryStore) UpdateEvent(ctx context.Context, event *models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveEvent(event)
+}
+
+func (s *MemoryStore) saveEvent(event *models.Event) error {
+	if _, ok := s.events[event.ID]; !ok {
+		return ErrNotFound
+	}
+	event.UpdatedAt = time.Now()
+	stored := *event
+	stored.CandidateDates = nil
+	stored.Participants = nil
+	s.events[event.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) CountEventsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, event := range s.events {
+		if event.CreatedAt.Before(cutoff) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) DeleteEventsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, event := range s.events {
+		if !event.CreatedAt.Before(cutoff) {
+			continue
+		}
+		s.deleteEvent(id)
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (s *MemoryStore) DeleteEvent(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[id]; !ok {
+		return ErrNotFound
+	}
+	s.deleteEvent(id)
+	return nil
+}
+
+func (s *MemoryStore) ImportEvent(ctx context.Context, event *models.Event, feeds []models.RSSFeed, preserveIDs bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 途中で失敗して一部だけ保存されないよう、書き込む前に重複を確認する
+	if _, ok := s.events[event.ID]; ok {
+		return ErrConflict
+	}
+	if preserveIDs {
+		for _, candidateDate := range event.CandidateDates {
+			if _, ok := s.candidateDates[candidateDate.ID]; ok {
+				return ErrConflict
+			}
+		}
+		for _, participant := range event.Participants {
+			if _, ok := s.participants[participant.ID]; ok {
+				return ErrConflict
+			}
+			for _, response := range participant.Responses {
+				if _, ok := s.responses[response.ID]; ok {
+					return ErrConflict
+				}
+			}
+		}
+		for _, feed := range feeds {
+			if _, ok := s.feeds[feed.ID]; ok {
+				return ErrConflict
+			}
+		}
+	}
+	event.WorkspaceID = cloneID(event.WorkspaceID)
+	requested := func(id uint) uint {
+		if preserveIDs {
+			return id
+		}
+		return 0
+	}
+
+	candidateDateIDs := make(map[uint]uint, len(event.CandidateDates))
+	for i := range event.CandidateDates {
+		candidateDate := &event.CandidateDates[i]
+		original := candidateDate.ID
+		candidateDate.ID = nextID(&s.lastCandidateDateID, requested(candidateDate.ID))
+		candidateDate.EventID = event.ID
+		candidateDateIDs[original] = candidateDate.ID
+		stored := *candidateDate
+		stored.Responses = nil
+		s.candidateDates[stored.ID] = stored
+	}
+
+	for i := range event.Participants {
+		participant := &event.Participants[i]
+		participant.ID = nextID(&s.lastParticipantID, requested(participant.ID))
+		participant.EventID = event.ID
+		for j := range participant.Responses {
+			response := &participant.Responses[j]
+			response.ID = nextID(&s.lastResponseID, requested(response.ID))
+			response.ParticipantID = participant.ID
+			response.CandidateDateID = candidateDateIDs[response.CandidateDateID]
+			s.responses[response.ID] = *response
+		}
+		stored := *participant
+		stored.Responses = nil
+		s.participants[stored.ID] = stored
+	}
+
+	for i := range feeds {
+		feeds[i].ID = nextID(&s.lastFeedID, requested(feeds[i].ID))
+		feeds[i].EventID = event.ID
+		s.feeds[feeds[i].ID] = feeds[i]
+	}
+
+	stored := *event
+	stored.CandidateDates = nil
+	stored.Participants = nil
+	s.events[event.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) deleteEvent(id string) {
+	for candidateDateID, candidateDate := range s.candidateDates {
+		if candidateDate.EventID == id {
+			delete(s.candidateDates, candidateDateID)
+		}
+	}
+	for participantID, participant := range s.participants {
+		if participant.EventID != id {
+			continue
+		}
+		for responseID, response := range s.responses {
+			if response.ParticipantID == participantID {
+				delete(s.responses, responseID)
+			}
+		}
+		delete(s.participants, participantID)
+	}
+	for feedID, feed := range s.feeds {
+		if feed.EventID == id {
+			delete(s.feeds, feedID)
+		}
+	}
+	delete(s.events, id)
+}
+
+func (s *MemoryStore) ListEventsAwaitingDeadline(ctx context.Context) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.events,
+		func(e models.Event) bool { return e.DeadlineEnable && !e.DeadlineReached },
+		func(a, b models.Event) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	), nil
+}
+
+func (s *MemoryStore) ListEvents(ctx context.Context, q EventQuery) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	inRange := func(t *time.Time, after, before *time.Time) bool {
+		if after == nil && before == nil {
+			return true
+		}
+		return t != nil && (after == nil || !t.Before(*after)) && (before == nil || t.Before(*before))
+	}
+	visible := func(e models.Event) bool {
+		if e.OwnerID != nil && *e.OwnerID == q.VisibleTo {
+			return true
+		}
+		_, member := s.members[memberKey{workspaceID: ptrValue(e.WorkspaceID), userID: q.VisibleTo}]
+		return e.WorkspaceID != nil && member
+	}
+	decided := func(e models.Event) bool { return e.DeadlineReached || e.AutoDecisionReached }
+
+	compare := func(a, b models.Event) int {
+		var c int
+		switch q.Sort {
+		case SortDeadline:
+			c = ptrValue(a.Deadline).Compare(ptrValue(b.Deadline))
+		case SortTitle:
+			c = cmp.Compare(a.Title, b.Title)
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		c = cmp.Or(c, cmp.Compare(a.ID, b.ID))
+		if q.Desc {
+			return -c
+		}
+		return c
+	}
+	var after models.Event
+	if q.After != nil {
+		after = models.Event{ID: q.After.ID, Title: q.After.Title, CreatedAt: q.After.Time, Deadline: &q.After.Time}
+	}
+
+	events := sortedValues(s.events, func(e models.Event) bool {
+		return (q.VisibleTo == "" || visible(e)) &&
+			(q.OwnerID == "" || ptrValue(e.OwnerID) == q.OwnerID) &&
+			(q.WorkspaceID == "" || ptrValue(e.WorkspaceID) == q.WorkspaceID) &&
+			(q.Status != StatusOpen || !decided(e)) &&
+			(q.Status != StatusDecided || decided(e)) &&
+			(q.Title == "" || strings.Contains(strings.ToLower(e.Title), strings.ToLower(q.Title))) &&
+			inRange(&e.CreatedAt, q.CreatedAfter, q.CreatedBefore) &&
+			inRange(e.Deadline, q.DeadlineAfter, q.DeadlineBefore) &&
+			(q.Sort != SortDeadline || e.Deadline != nil) &&
+			(q.After == nil || compare(e, after) > 0)
+	}, compare)
+
+	if q.Limit > 0 && len(events) > q.Limit {
+		events = events[:q.Limit]
+	}
+	return events, nil
+}
+
+func ptrValue[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func (s *MemoryStore) ListEventsByOwner(ctx context.Context, ownerID string) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.events,
+		func(e models.Event) bool { return e.OwnerID != nil && *e.OwnerID == ownerID },
+		func(a, b models.Event) int { return b.CreatedAt.Compare(a.CreatedAt) },
+	), nil
+}
+
+func (s *MemoryStore) ListEventsByWorkspace(ctx context.Context, workspaceID string) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.events,
+		func(e models.Event) bool { return e.WorkspaceID != nil && *e.WorkspaceID == workspaceID },
+		func(a, b models.Event) int { return b.CreatedAt.Compare(a.CreatedAt) },
+	), nil
+}
+
+func (s *MemoryStore) ListEventsByParticipant(ctx context.Context, userID string) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	participants := sortedValues(s.participants,
+		func(p models.Participant) bool { return p.UserID != nil && *p.UserID == userID },
+		func(a, b models.Participant) int { return b.CreatedAt.Compare(a.CreatedAt) },
+	)
+	events := []models.Event{}
+	for _, participant := range participants {
+		if event, ok := s.events[participant.EventID]; ok {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (s *MemoryStore) ListCandidateDates(ctx context.Context, eventID string) ([]models.CandidateDate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	candidateDates := s.candidateDatesOf(eventID)
+	for i := range candidateDates {
+		candidateDates[i].Responses = nil
+	}
+	return candidateDates, nil
+}
+
+func (s *MemoryStore) candidateDatesOf(eventID string) []models.CandidateDate {
+	candidateDates := sortedValues(s.candidateDates,
+		func(cd models.CandidateDate) bool { return cd.EventID == eventID && !cd.DeletedAt.Valid },
+		func(a, b models.CandidateDate) int { return cmp.Compare(a.ID, b.ID) },
+	)
+	for i := range candidateDates {
+		id := candidateDates[i].ID
+		candidateDates[i].Responses = sortedValues(s.responses,
+			func(r models.Response) bool { return r.CandidateDateID == id },
+			byResponseID,
+		)
+	}
+	return candidateDates
+}
+
+func (s *MemoryStore) participantsOf(eventID string) []models.Participant {
+	participants := sortedValues(s.participants,
+		func(p models.Participant) bool { return p.EventID == eventID },
+		func(a, b models.Participant) int { return cmp.Compare(a.ID, b.ID) },
+	)
+	for i := range participants {
+		id := participants[i].ID
+		participants[i].Responses = sortedValues(s.responses,
+			func(r models.Response) bool { return r.ParticipantID == id },
+			byResponseID,
+		)
+	}
+	return participants
+}
+
+func (s *MemoryStore) TallyResponses(ctx context.Context, eventID string) ([]CandidateDateTally, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	candidateDates := s.candidateDatesOf(eventID)
+	tallies := make([]CandidateDateTally, len(candidateDates))
+	for i, candidateDate := range candidateDates {
+		tallies[i] = CandidateDateTally{CandidateDateID: candidateDate.ID, DateTime: candidateDate.DateTime}
+		for _, response := range candidateDate.Responses {
+			switch response.Status {
+			case "available":
+				tallies[i].Available++
+			case "maybe":
+				tallies[i].Maybe++
+			case "unavailable":
+				tallies[i].Unavailable++
+			}
+		}
+	}
+	return tallies, nil
+}
+
+func (s *MemoryStore) CreateParticipant(ctx context.Context, participant *models.Participant, limit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.participants[participant.ID]; ok && participant.ID != 0 {
+		return ErrConflict
+	}
+	if limit > 0 && len(s.participantsOf(participant.EventID)) >= limit {
+		return ErrLimitReached
+	}
+	if participant.UserID != nil {
+		for _, existing := range s.participants {
+			if existing.EventID == participant.EventID && existing.UserID != nil && *existing.UserID == *participant.UserID {
+				return ErrConflict
+			}
+		}
+	}
+
+	now := time.Now()
+	participant.EventID = strings.Clone(participant.EventID)
+	participant.ID = nextID(&s.lastParticipantID, participant.ID)
+	participant.CreatedAt, participant.UpdatedAt = now, now
+	for i := range participant.Responses {
+		response := &participant.Responses[i]
+		response.ID = nextID(&s.lastResponseID, response.ID)
+		response.ParticipantID = participant.ID
+		response.CreatedAt, response.UpdatedAt = now, now
+		s.responses[response.ID] = *response
+	}
+
+	stored := *participant
+	stored.Responses = nil
+	s.participants[participant.ID] = stored
+	return nil
+}
+
+func (s *MemoryStore) ListParticipants(ctx context.Context, eventID string, withResponses bool) ([]models.Participant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	participants := s.participantsOf(eventID)
+	if !withResponses {
+		for i := range participants {
+			participants[i].Responses = nil
+		}
+	}
+	return participants, nil
+}
+
+func (s *MemoryStore) CountParticipants(ctx context.Context, eventID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, participant := range s.participants {
+		if participant.EventID == eventID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) CountParticipantsByEvent(ctx context.Context, eventIDs []string) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := map[string]int64{}
+	for _, participant := range s.participants {
+		if slices.Contains(eventIDs, participant.EventID) {
+			counts[participant.EventID]++
+		}
+	}
+	return counts, nil
+}
+
+func (s *MemoryStore) ListFeeds(ctx context.Context, eventID string) ([]models.RSSFeed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.feeds,
+		func(f models.RSSFeed) bool { return f.EventID == eventID },
+		func(a, b models.RSSFeed) int { return cmp.Compare(a.ID, b.ID) },
+	), nil
+}
+
+func (s *MemoryStore) RecordDecision(ctx context.Context, eventID string, flag DecisionFlag, feed *models.RSSFeed) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[eventID]
+	if !ok {
+		return ErrConflict
+	}
+	reached := &event.AutoDecisionReached
+	if flag == DecisionDeadline {
+		reached = &event.DeadlineReached
+	}
+	if *reached {
+		return ErrConflict
+	}
+	*reached = true
+	// eventID はリクエストのパラメータの場合があるので、保存済みの ID をキーにする
+	s.events[event.ID] = event
+
+	feed.EventID = strings.Clone(feed.EventID)
+	feed.ID = nextID(&s.lastFeedID, feed.ID)
+	if feed.CreatedAt.IsZero() {
+		feed.CreatedAt = time.Now()
+	}
+	s.feeds[feed.ID] = *feed
+	return nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.ID == user.ID || existing.Email == user.Email {
+			return ErrConflict
+		}
+	}
+	now := time.Now()
+	user.CreatedAt, user.UpdatedAt = now, now
+	s.users[user.ID] = *user
+	return nil
+}
+
+func (s *MemoryStore) GetUser(ctx context.Context, id string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, identity := range s.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			if user, ok := s.users[identity.UserID]; ok {
+				return &user, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *MemoryStore) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.identities {
+		if existing.Issuer == identity.Issuer && existing.Subject == identity.Subject {
+			return ErrConflict
+		}
+	}
+	identity.ID = nextID(&s.lastIdentityID, identity.ID)
+	identity.CreatedAt = time.Now()
+	s.identities[identity.ID] = *identity
+	return nil
+}
+
+func (s *MemoryStore) CreateWorkspace(ctx context.Context, workspace *models.Workspace, ownerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workspaces[workspace.ID]; ok {
+		return ErrConflict
+	}
+	now := time.Now()
+	workspace.CreatedAt, workspace.UpdatedAt = now, now
+	s.workspaces[workspace.ID] = *workspace
+	s.members[memberKey{workspace.ID, ownerID}] = models.WorkspaceMember{
+		WorkspaceID: workspace.ID,
+		UserID:      ownerID,
+		Role:        models.WorkspaceRoleOwner,
+		CreatedAt:   now,
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetWorkspace(ctx context.Context, id string) (*models.Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workspace, ok := s.workspaces[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &workspace, nil
+}
+
+func (s *MemoryStore) UpdateWorkspace(ctx context.Context, workspace *models.Workspace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workspaces[workspace.ID]; !ok {
+		return ErrNotFound
+	}
+	workspace.UpdatedAt = time.Now()
+	s.workspaces[workspace.ID] = *workspace
+	return nil
+}
+
+func (s *MemoryStore) DeleteWorkspace(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workspaces[id]; !ok {
+		return ErrNotFound
+	}
+	for eventID, event := range s.events {
+		if event.WorkspaceID != nil && *event.WorkspaceID == id {
+			s.deleteEvent(eventID)
+		}
+	}
+	for seriesID, series := range s.series {
+		if series.WorkspaceID != nil && *series.WorkspaceID == id {
+			delete(s.series, seriesID)
+		}
+	}
+	for key := range s.members {
+		if key.workspaceID == id {
+			delete(s.members, key)
+		}
+	}
+	delete(s.workspaces, id)
+	return nil
+}
+
+func (s *MemoryStore) AddWorkspaceMember(ctx context.Context, member *models.WorkspaceMember) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	member.WorkspaceID, member.UserID = strings.Clone(member.WorkspaceID), strings.Clone(member.UserID)
+	key := memberKey{member.WorkspaceID, member.UserID}
+	if _, ok := s.members[key]; ok {
+		return ErrConflict
+	}
+	member.CreatedAt = time.Now()
+	stored := *member
+	stored.Workspace, stored.User = nil, nil
+	s.members[key] = stored
+	return nil
+}
+
+func (s *MemoryStore) GetWorkspaceMember(ctx context.Context, workspaceID, userID string) (*models.WorkspaceMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	member, ok := s.members[memberKey{workspaceID, userID}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &member, nil
+}
+
+func (s *MemoryStore) UpdateWorkspaceMember(ctx context.Context, member *models.WorkspaceMember) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memberKey{member.WorkspaceID, member.UserID}
+	stored, ok := s.members[key]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Role = member.Role
+	s.members[key] = stored
+	return nil
+}
+
+func (s *MemoryStore) RemoveWorkspaceMember(ctx context.Context, workspaceID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := memberKey{workspaceID, userID}
+	if _, ok := s.members[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.members, key)
+	return nil
+}
+
+func byMemberJoined(a, b models.WorkspaceMember) int {
+	return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.UserID, b.UserID))
+}
+
+func (s *MemoryStore) ListWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := sortedValues(s.members,
+		func(m models.WorkspaceMember) bool { return m.WorkspaceID == workspaceID },
+		byMemberJoined,
+	)
+	for i := range members {
+		if user, ok := s.users[members[i].UserID]; ok {
+			members[i].User = &user
+		}
+	}
+	return members, nil
+}
+
+func (s *MemoryStore) ListWorkspacesByUser(ctx context.Context, userID string) ([]models.WorkspaceMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	memberships := sortedValues(s.members,
+		func(m models.WorkspaceMember) bool { return m.UserID == userID },
+		byMemberJoined,
+	)
+	for i := range memberships {
+		if workspace, ok := s.workspaces[memberships[i].WorkspaceID]; ok {
+			memberships[i].Workspace = &workspace
+		}
+	}
+	return memberships, nil
+}
+
+func (s *MemoryStore) CreateSeries(ctx context.Context, series *models.EventSeries) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.series[series.ID]; ok {
+		return ErrConflict
+	}
+	now := time.Now()
+	series.CreatedAt, series.UpdatedAt = now, now
+	s.series[series.ID] = *series
+	return nil
+}
+
+func (s *MemoryStore) GetSeries(ctx context.Context, id string) (*models.EventSeries, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	series, ok := s.series[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &series, nil
+}
+
+func (s *MemoryStore) UpdateSeries(ctx context.Context, series *models.EventSeries, reschedule bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.series[series.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	// next_run_at と last_run_at は保存済みの値を使う
+	if !reschedule {
+		series.NextRunAt = stored.NextRunAt
+	}
+	series.LastRunAt = stored.LastRunAt
+	series.OwnerID, series.WorkspaceID, series.CreatorName, series.CreatedAt = stored.OwnerID, stored.WorkspaceID, stored.CreatorName, stored.CreatedAt
+	series.UpdatedAt = time.Now()
+	s.series[series.ID] = *series
+	return nil
+}
+
+func (s *MemoryStore) DeleteSeries(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.series[id]; !ok {
+		return ErrNotFound
+	}
+	for eventID, event := range s.events {
+		if event.SeriesID != nil && *event.SeriesID == id {
+			event.SeriesID = nil
+			s.events[eventID] = event
+		}
+	}
+	delete(s.series, id)
+	return nil
+}
+
+func (s *MemoryStore) ListSeries(ctx context.Context, visibleTo string) ([]models.EventSeries, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.series,
+		func(series models.EventSeries) bool {
+			if series.OwnerID != nil && *series.OwnerID == visibleTo {
+				return true
+			}
+			_, member := s.members[memberKey{workspaceID: ptrValue(series.WorkspaceID), userID: visibleTo}]
+			return series.WorkspaceID != nil && member
+		},
+		func(a, b models.EventSeries) int {
+			return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+		},
+	), nil
+}
+
+func (s *MemoryStore) ListDueSeries(ctx context.Context, now time.Time) ([]models.EventSeries, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.series,
+		func(series models.EventSeries) bool { return series.Enabled && !series.NextRunAt.After(now) },
+		func(a, b models.EventSeries) int { return a.NextRunAt.Compare(b.NextRunAt) },
+	), nil
+}
+
+func (s *MemoryStore) CreateSeriesEvent(ctx context.Context, series *models.EventSeries, event *models.Event, nextRunAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.series[series.ID]
+	if !ok || !stored.NextRunAt.Equal(series.NextRunAt) {
+		return ErrConflict
+	}
+	if event != nil {
+		if err := s.createEvent(event); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	stored.NextRunAt, stored.LastRunAt = nextRunAt, &now
+	s.series[series.ID] = stored
+	series.NextRunAt, series.LastRunAt = nextRunAt, &now
+	return nil
+}
+
+func (s *MemoryStore) ListEventsBySeries(ctx context.Context, seriesID string) ([]models.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedValues(s.events,
+		func(e models.Event) bool { return e.SeriesID != nil && *e.SeriesID == seriesID },
+		func(a, b models.Event) int { return b.CreatedAt.Compare(a.CreatedAt) },
+	), nil
+}
+
+func (s *MemoryStore) CreateSession(ctx context.Context, session *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[session.ID]; ok {
+		return ErrConflict
+	}
+	session.CreatedAt = time.Now()
+	s.sessions[session.ID] = *session
+	return nil
+}
+
+func (s *MemoryStore) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (s *MemoryStore) DeleteSession(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemoryStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, session := range s.sessions {
+		if !session.ExpiresAt.After(now) {
+			delete(s.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"time"
+
+	"yotei-backend/models"
+)
+
+// イベント一覧の並び順
+const (
+	SortCreatedAt = "created_at"
+	SortDeadline  = "deadline"
+	SortTitle     = "title"
+)
+
+// イベント一覧の状態での絞り込み
+const (
+	StatusOpen    = "open"
+	StatusDecided = "decided"
+)
+
+// EventQuery はイベント一覧の検索条件。ゼロ値の項目は絞り込みに使わない
+type EventQuery struct {
+	// 指定したユーザーが作成したイベントと、所属するワークスペースのイベントに限る
+	VisibleTo   string
+	OwnerID     string
+	WorkspaceID string
+	Status      string
+	// タイトルの部分一致（大文字と小文字は区別しない）
+	Title          string
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	DeadlineAfter  *time.Time
+	DeadlineBefore *time.Time
+
+	// 締切で並べる場合は締切のあるイベントだけを返す
+	Sort string
+	Desc bool
+	// 前のページの最後のイベント。この次から返す
+	After *EventCursor
+	Limit int
+}
+
+// EventCursor はキーセットページネーションの位置。並び順のキーと ID の組
+type EventCursor struct {
+	Time  time.Time `json:"t,omitzero"`
+	Title string    `json:"s,omitempty"`
+	ID    string    `json:"id"`
+}
+
+// CursorFor はイベントの並び順のキーからカーソルを作る
+func (q EventQuery) CursorFor(event *models.Event) *EventCursor {
+	cursor := &EventCursor{ID: event.ID}
+	switch q.Sort {
+	case SortDeadline:
+		if event.Deadline != nil {
+			cursor.Time = *event.Deadline
+		}
+	case SortTitle:
+		cursor.Title = event.Title
+	default:
+		cursor.Time = event.CreatedAt
+	}
+	return cursor
+}
+
+// CandidateDateTally は候補日ごとの回答数の集計
+type CandidateDateTally struct {
+	CandidateDateID uint      `json:"candidate_date_id"`
+	DateTime        time.Time `json:"date_time"`
+	Available       int64     `json:"available"`
+	Maybe           int64     `json:"maybe"`
+	Unavailable     int64     `json:"unavailable"`
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"yotei-backend/models"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+	ErrConflict = errors.New("record already exists")
+	// 上限に達しているため作成できない
+	ErrLimitReached = errors.New("limit reached")
+)
+
+// DecisionFlag は日程が決まったときに立てるイベントのフラグ（列名）
+type DecisionFlag string
+
+const (
+	DecisionAutoDecision DecisionFlag = "auto_decision_reached"
+	DecisionDeadline     DecisionFlag = "deadline_reached"
+)
+
+// Store はハンドラとスケジューラが使う永続化層のインターフェース
+type Store interface {
+	// イベント
+	CreateEvent(ctx context.Context, event *models.Event) error
+	GetEvent(ctx context.Context, id string) (*models.Event, error)
+	// 候補日・参加者とそれぞれの回答を含めて取得する
+	GetEventDetails(ctx context.Context, id string) (*models.Event, error)
+	UpdateEvent(ctx context.Context, event *models.Event) error
+	// 作成日時が cutoff より前のイベントを関連データごと削除する
+	CountEventsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	DeleteEventsCreatedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	// 締切が有効でまだ締切処理が済んでいないイベント
+	ListEventsAwaitingDeadline(ctx context.Context) ([]models.Event, error)
+	// 条件に一致するイベントを q.Sort の順に最大 q.Limit 件返す
+	ListEvents(ctx context.Context, q EventQuery) ([]models.Event, error)
+	// ユーザーが作成したイベントを新しい順に返す
+	ListEventsByOwner(ctx context.Context, ownerID string) ([]models.Event, error)
+	// ワークスペースのイベントを新しい順に返す
+	ListEventsByWorkspace(ctx context.Context, workspaceID string) ([]models.Event, error)
+	// ユーザーが回答したイベントを回答の新しい順に返す
+	ListEventsByParticipant(ctx context.Context, userID string) ([]models.Event, error)
+	// イベントを関連データごと削除する
+	DeleteEvent(ctx context.Context, id string) error
+	// 書き出したイベントを候補日・参加者・回答・通知ごと作成する。作成日時などはそのまま保存する。
+	// preserveIDs が false の場合は子のレコードに新しい ID を振り、回答の参照先を付け替える
+	ImportEvent(ctx context.Context, event *models.Event, feeds []models.RSSFeed, preserveIDs bool) error
+
+	// 候補日を ID 順に返す。回答は読み込まない
+	ListCandidateDates(ctx context.Context, eventID string) ([]models.CandidateDate, error)
+
+	// 候補日ごとの回答数を候補日の ID 順に返す。回答のない候補日も含む
+	TallyResponses(ctx context.Context, eventID string) ([]CandidateDateTally, error)
+
+	// 参加者と回答
+	// 参加者を回答ごと作成する。limit が 0 より大きく、イベントの参加者がすでに limit 人いる場合は ErrLimitReached
+	CreateParticipant(ctx context.Context, participant *models.Participant, limit int) error
+	// 参加順に返す。withResponses が false の場合は回答を読み込まない
+	ListParticipants(ctx context.Context, eventID string, withResponses bool) ([]models.Participant, error)
+	CountParticipants(ctx context.Context, eventID string) (int64, error)
+	// イベントIDごとの参加者数。参加者がいないイベントは含まない
+	CountParticipantsByEvent(ctx context.Context, eventIDs []string) (map[string]int64, error)
+
+	// RSSフィード
+	ListFeeds(ctx context.Context, eventID string) ([]models.RSSFeed, error)
+
+	// イベントの決定フラグを立て、日程決定の通知を同時に作成する。
+	// フラグがすでに立っているかイベントがない場合は ErrConflict を返し、通知は作成しない
+	RecordDecision(ctx context.Context, eventID string, flag DecisionFlag, feed *models.RSSFeed) error
+
+	// ユーザー（メールアドレスが重複する場合は ErrConflict）
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	// OpenID Connect のアカウントに紐づいたユーザー
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
+	// アカウントがすでに紐づいている場合は ErrConflict
+	CreateIdentity(ctx context.Context, identity *models.UserIdentity) error
+
+	// ワークスペース。作成したユーザーをオーナーとして追加する
+	CreateWorkspace(ctx context.Context, workspace *models.Workspace, ownerID string) error
+	GetWorkspace(ctx context.Context, id string) (*models.Workspace, error)
+	UpdateWorkspace(ctx context.Context, workspace *models.Workspace) error
+	// ワークスペースをメンバー・イベント・シリーズごと削除する
+	DeleteWorkspace(ctx context.Context, id string) error
+
+	// ワークスペースのメンバー（すでにメンバーの場合は ErrConflict）
+	AddWorkspaceMember(ctx context.Context, member *models.WorkspaceMember) error
+	GetWorkspaceMember(ctx context.Context, workspaceID, userID string) (*models.WorkspaceMember, error)
+	UpdateWorkspaceMember(ctx context.Context, member *models.WorkspaceMember) error
+	RemoveWorkspaceMember(ctx context.Context, workspaceID, userID string) error
+	// ユーザーを含めて参加順に返す
+	ListWorkspaceMembers(ctx context.Context, workspaceID string) ([]models.WorkspaceMember, error)
+	// ユーザーが所属するワークスペースをワークスペースを含めて返す
+	ListWorkspacesByUser(ctx context.Context, userID string) ([]models.WorkspaceMember, error)
+
+	// イベントシリーズ
+	CreateSeries(ctx context.Context, series *models.EventSeries) error
+	GetSeries(ctx context.Context, id string) (*models.EventSeries, error)
+	// シリーズの利用者が変更できる項目を更新し、保存後の値を series に読み直す。
+	// reschedule が true の場合は next_run_at も更新する
+	UpdateSeries(ctx context.Context, series *models.EventSeries, reschedule bool) error
+	// シリーズを削除する。作成済みのイベントは残し、シリーズとの紐づけだけを外す
+	DeleteSeries(ctx context.Context, id string) error
+	// ユーザーが作成したシリーズと、所属するワークスペースのシリーズを作成順に返す
+	ListSeries(ctx context.Context, visibleTo string) ([]models.EventSeries, error)
+	// 有効で次の作成日時が now 以前のシリーズ
+	ListDueSeries(ctx context.Context, now time.Time) ([]models.EventSeries, error)
+	// シリーズのイベントを作成し、次の作成日時を nextRunAt に進める。event が nil の場合は作成日時だけを進める。
+	// 別のプロセスが先に作成して次の作成日時が series.NextRunAt から変わっていた場合は ErrConflict
+	CreateSeriesEvent(ctx context.Context, series *models.EventSeries, event *models.Event, nextRunAt time.Time) error
+	// シリーズから作成したイベントを新しい順に返す
+	ListEventsBySeries(ctx context.Context, seriesID string) ([]models.Event, error)
+
+	// ログインセッション
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	DeleteSession(ctx context.Context, id string) error
+	// 期限が now 以前のセッションを削除し、削除した件数を返す
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+}
+
+var (
+	_ Store = (*GormStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
@@ -0,0 +1,76 @@
+package telemetry
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "telemetry:span"
+
+// GormPlugin は GORM のクエリごとにクライアントスパンを作る。
+// Preload の各クエリも別のスパンになる。バインド変数には個人情報が含まれうるので記録しない
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "telemetry"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("telemetry:before_create", startQuerySpan("create")),
+		callbacks.Create().After("gorm:create").Register("telemetry:after_create", endQuerySpan),
+		callbacks.Query().Before("gorm:query").Register("telemetry:before_query", startQuerySpan("select")),
+		callbacks.Query().After("gorm:query").Register("telemetry:after_query", endQuerySpan),
+		callbacks.Update().Before("gorm:update").Register("telemetry:before_update", startQuerySpan("update")),
+		callbacks.Update().After("gorm:update").Register("telemetry:after_update", endQuerySpan),
+		callbacks.Delete().Before("gorm:delete").Register("telemetry:before_delete", startQuerySpan("delete")),
+		callbacks.Delete().After("gorm:delete").Register("telemetry:after_delete", endQuerySpan),
+		callbacks.Row().Before("gorm:row").Register("telemetry:before_row", startQuerySpan("row")),
+		callbacks.Row().After("gorm:row").Register("telemetry:after_row", endQuerySpan),
+		callbacks.Raw().Before("gorm:raw").Register("telemetry:before_raw", startQuerySpan("raw")),
+		callbacks.Raw().After("gorm:raw").Register("telemetry:after_raw", endQuerySpan),
+	)
+}
+
+func startQuerySpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		name := operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		ctx, span := Tracer().Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameKey.String(db.Dialector.Name()),
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endQuerySpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,62 @@
+package telemetry
+
+import (
+	"net/http"
+	"strings"
+
+	"yotei-backend/clientip"
+	"yotei-backend/httperror"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware はリクエストごとにサーバースパンを開始し、UserContext に載せる。
+// traceparent ヘッダがあれば呼び出し元のトレースを引き継ぐ
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		carrier := propagation.HeaderCarrier(http.Header{})
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			carrier.Set(string(key), string(value))
+		})
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), carrier)
+
+		method := strings.Clone(c.Method())
+		ctx, span := Tracer().Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(strings.Clone(c.Path())),
+				semconv.ClientAddress(clientip.IP(c)),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		// エラーは内側の httperror.Middleware がレスポンスにしてあるので、ここではステータスコードを読むだけにする
+		err := c.Next()
+		if handlerErr := httperror.Err(c); handlerErr != nil {
+			span.RecordError(handlerErr)
+		}
+
+		status := c.Response().StatusCode()
+		route := strings.Clone(c.Route().Path)
+		span.SetName(method + " " + route)
+		span.SetAttributes(
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(status),
+		)
+		if requestID, ok := c.Locals("requestid").(string); ok {
+			span.SetAttributes(attribute.String("http.request_id", requestID))
+		}
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}